@@ -205,8 +205,18 @@ func (r *ReadWaitCloser) Close() error {
 // from the returned reader via through the corresponding read call
 // (e.g. io.Copy or io.ReadAll).
 func CompressWithGzip(data io.Reader) io.ReadCloser {
+	return CompressWithGzipLevel(data, gzip.DefaultCompression)
+}
+
+// CompressWithGzipLevel is similar to CompressWithGzip but uses the given
+// compression level. Invalid levels are reported as error when reading.
+func CompressWithGzipLevel(data io.Reader, level int) io.ReadCloser {
 	pipeReader, pipeWriter := io.Pipe()
-	gzipWriter := gzip.NewWriter(pipeWriter)
+	gzipWriter, err := gzip.NewWriterLevel(pipeWriter, level)
+	if err != nil {
+		pipeWriter.CloseWithError(err)
+		return pipeReader
+	}
 
 	// Start copying from the uncompressed reader to the output reader
 	// in the background until the input reader is closed (or errors out).
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os/exec"
 	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -187,6 +188,24 @@ func TestCompressWithGzip(t *testing.T) {
 	require.Equal(t, testData, string(output))
 }
 
+func TestCompressWithGzipLevel(t *testing.T) {
+	testData := strings.Repeat("the quick brown fox jumps over the lazy dog", 100)
+
+	for _, level := range []int{gzip.BestSpeed, gzip.BestCompression} {
+		outputBuffer := CompressWithGzipLevel(bytes.NewBufferString(testData), level)
+		gzipReader, err := gzip.NewReader(outputBuffer)
+		require.NoError(t, err)
+
+		output, err := io.ReadAll(gzipReader)
+		require.NoError(t, err)
+		require.NoError(t, gzipReader.Close())
+		require.Equal(t, testData, string(output))
+	}
+
+	_, err := io.ReadAll(CompressWithGzipLevel(bytes.NewBufferString(testData), 42))
+	require.ErrorContains(t, err, "invalid compression level")
+}
+
 type mockReader struct {
 	err    error
 	ncalls uint64 // record the number of calls to Read
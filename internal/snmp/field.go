@@ -23,6 +23,10 @@ type Field struct {
 	// off the OID prefix, and use the remainder as the index. For multiple fields
 	// to show up in the same row, they must share the same index.
 	Oid string
+	// OidEnd is an optional boundary for walking the OID. The walk stops at the
+	// first OID greater or equal to this boundary, i.e. the boundary itself is
+	// excluded.
+	OidEnd string
 	// OidIndexSuffix is the trailing sub-identifier on a table record OID that will be stripped off to get the record's index.
 	OidIndexSuffix string
 	// OidIndexLength specifies the length of the index in OID path segments. It can be used to remove sub-identifiers that vary in content or length.
@@ -81,6 +85,15 @@ func (f *Field) Init(tr Translator) error {
 		//TODO use textual convention conversion from the MIB
 	}
 
+	// translate the walk boundary if given as textual OID
+	if strings.ContainsAny(f.OidEnd, ":abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+		_, oidNum, _, _, err := f.translator.SnmpTranslate(f.OidEnd)
+		if err != nil {
+			return fmt.Errorf("translating end OID: %w", err)
+		}
+		f.OidEnd = oidNum
+	}
+
 	if f.SecondaryIndexTable && f.SecondaryIndexUse {
 		return errors.New("SecondaryIndexTable and UseSecondaryIndex are exclusive")
 	}
@@ -1,8 +1,10 @@
 package snmp
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -150,6 +152,13 @@ func (t Table) Build(gs Connection, walk bool) (*RTable, error) {
 			oid = "." + f.Oid
 		}
 
+		var end string
+		if f.OidEnd != "" && f.OidEnd[0] != '.' {
+			end = "." + f.OidEnd
+		} else {
+			end = f.OidEnd
+		}
+
 		// ifv contains a mapping of table OID index to field value
 		ifv := map[string]interface{}{}
 
@@ -183,6 +192,9 @@ func (t Table) Build(gs Connection, walk bool) (*RTable, error) {
 				if len(ent.Name) <= len(oid) || ent.Name[:len(oid)+1] != oid+"." {
 					return &walkError{} // break the walk
 				}
+				if end != "" && compareOids(ent.Name, end) >= 0 {
+					return &walkError{} // break the walk at the boundary
+				}
 
 				idx := ent.Name[len(oid):]
 				if f.OidIndexSuffix != "" {
@@ -313,3 +325,25 @@ func (e *walkError) Error() string {
 func (e *walkError) Unwrap() error {
 	return e.err
 }
+
+// compareOids compares the two numeric OIDs sub-identifier by sub-identifier
+// and returns -1, 0 or +1 if a is lexicographically less, equal or greater
+// than b. A parent OID is less than all of its children.
+func compareOids(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "."), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "."), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		va, erra := strconv.ParseUint(pa[i], 10, 64)
+		vb, errb := strconv.ParseUint(pb[i], 10, 64)
+		if erra != nil || errb != nil {
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if va != vb {
+			return cmp.Compare(va, vb)
+		}
+	}
+	return cmp.Compare(len(pa), len(pb))
+}
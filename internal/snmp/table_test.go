@@ -1,8 +1,10 @@
 package snmp
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/gosnmp/gosnmp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -244,3 +246,69 @@ func TestTableJoinNoIndexAsTag_walk(t *testing.T) {
 	require.Contains(t, tb.Rows, rtr2)
 	require.Contains(t, tb.Rows, rtr3)
 }
+
+// orderedSNMPConnection walks the OIDs in the given order like a real agent
+type orderedSNMPConnection struct {
+	testSNMPConnection
+	oids []string
+}
+
+func (c *orderedSNMPConnection) Walk(oid string, wf gosnmp.WalkFunc) error {
+	for _, o := range c.oids {
+		if !strings.HasPrefix(o, oid) {
+			continue
+		}
+		if err := wf(gosnmp.SnmpPDU{Name: o, Value: c.values[o]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTableOidEnd_walk(t *testing.T) {
+	conn := &orderedSNMPConnection{
+		testSNMPConnection: testSNMPConnection{
+			values: map[string]interface{}{
+				".1.0.0.3.1.1.9":  "instance9",
+				".1.0.0.3.1.1.10": "instance10",
+				".1.0.0.3.1.1.11": "instance11",
+				".1.0.0.3.1.1.20": "instance20",
+			},
+		},
+		oids: []string{".1.0.0.3.1.1.9", ".1.0.0.3.1.1.10", ".1.0.0.3.1.1.11", ".1.0.0.3.1.1.20"},
+	}
+
+	tbl := Table{
+		Name:       "mytable",
+		IndexAsTag: true,
+		Fields: []Field{
+			{
+				Name:   "myfield1",
+				Oid:    ".1.0.0.3.1.1",
+				OidEnd: "1.0.0.3.1.1.11",
+				IsTag:  true,
+			},
+		},
+	}
+
+	tb, err := tbl.Build(conn, true)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []RTableRow{
+		{
+			Tags:   map[string]string{"myfield1": "instance9", "index": "9"},
+			Fields: map[string]interface{}{},
+		},
+		{
+			Tags:   map[string]string{"myfield1": "instance10", "index": "10"},
+			Fields: map[string]interface{}{},
+		},
+	}, tb.Rows)
+}
+
+func TestCompareOids(t *testing.T) {
+	require.Equal(t, 0, compareOids(".1.3.6.1", "1.3.6.1"))
+	require.Equal(t, -1, compareOids(".1.3.6.9", ".1.3.6.10"))
+	require.Equal(t, 1, compareOids(".1.3.7", ".1.3.6.10"))
+	require.Equal(t, -1, compareOids(".1.3.6", ".1.3.6.1"))
+	require.Equal(t, 1, compareOids(".1.3.6.1", ".1.3.6"))
+}
@@ -1,8 +1,9 @@
 package rollbar
 
 import (
+	"crypto/subtle"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -14,9 +15,10 @@ import (
 )
 
 type RollbarWebhook struct {
-	Path string
-	acc  telegraf.Accumulator
-	log  telegraf.Logger
+	Path        string
+	AccessToken string `toml:"access_token"`
+	acc         telegraf.Accumulator
+	log         telegraf.Logger
 	auth.BasicAuth
 }
 
@@ -30,7 +32,7 @@ func (rb *RollbarWebhook) Register(router *mux.Router, acc telegraf.Accumulator,
 func (rb *RollbarWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	if !rb.Verify(r) {
+	if !rb.Verify(r) || !rb.verifyAccessToken(r) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -50,6 +52,7 @@ func (rb *RollbarWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
 
 	event, err := NewEvent(dummyEvent, data)
 	if err != nil {
+		rb.log.Debugf("Ignoring event: %v", err)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -59,6 +62,16 @@ func (rb *RollbarWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// verifyAccessToken checks the access token sent by Rollbar against the
+// configured one. Requests are accepted if no token is configured.
+func (rb *RollbarWebhook) verifyAccessToken(r *http.Request) bool {
+	if rb.AccessToken == "" {
+		return true
+	}
+	token := r.Header.Get("X-Rollbar-Access-Token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(rb.AccessToken)) == 1
+}
+
 func generateEvent(event Event, data []byte) (Event, error) {
 	err := json.Unmarshal(data, event)
 	if err != nil {
@@ -75,7 +88,13 @@ func NewEvent(dummyEvent *DummyEvent, data []byte) (Event, error) {
 		return generateEvent(&Occurrence{}, data)
 	case "deploy":
 		return generateEvent(&Deploy{}, data)
+	case "reactivated_item":
+		return generateEvent(&ReactivatedItem{}, data)
+	case "resolved_item":
+		return generateEvent(&ResolvedItem{}, data)
+	case "test":
+		return generateEvent(&Test{}, data)
 	default:
-		return nil, errors.New("Not implemented type: " + dummyEvent.EventName)
+		return nil, fmt.Errorf("not implemented type %q", dummyEvent.EventName)
 	}
 }
@@ -2,6 +2,15 @@ package rollbar
 
 import "strconv"
 
+// levelValues maps the Rollbar item levels to their numeric representation
+var levelValues = map[string]int{
+	"critical": 50,
+	"error":    40,
+	"warning":  30,
+	"info":     20,
+	"debug":    10,
+}
+
 type Event interface {
 	Tags() map[string]string
 	Fields() map[string]interface{}
@@ -17,10 +26,12 @@ type NewItemDataItemLastOccurrence struct {
 }
 
 type NewItemDataItem struct {
-	ID             int                           `json:"id"`
-	Environment    string                        `json:"environment"`
-	ProjectID      int                           `json:"project_id"`
-	LastOccurrence NewItemDataItemLastOccurrence `json:"last_occurrence"`
+	ID               int                           `json:"id"`
+	Environment      string                        `json:"environment"`
+	ProjectID        int                           `json:"project_id"`
+	Title            *string                       `json:"title"`
+	TotalOccurrences *int                          `json:"total_occurrences"`
+	LastOccurrence   NewItemDataItemLastOccurrence `json:"last_occurrence"`
 }
 
 type NewItemData struct {
@@ -43,9 +54,19 @@ func (ni *NewItem) Tags() map[string]string {
 }
 
 func (ni *NewItem) Fields() map[string]interface{} {
-	return map[string]interface{}{
+	fields := map[string]interface{}{
 		"id": ni.Data.Item.ID,
 	}
+	if ni.Data.Item.Title != nil {
+		fields["title"] = *ni.Data.Item.Title
+	}
+	if ni.Data.Item.TotalOccurrences != nil {
+		fields["total_occurrences"] = *ni.Data.Item.TotalOccurrences
+	}
+	if v, found := levelValues[ni.Data.Item.LastOccurrence.Level]; found {
+		fields["level_value"] = v
+	}
+	return fields
 }
 
 type OccurrenceDataOccurrence struct {
@@ -54,9 +75,11 @@ type OccurrenceDataOccurrence struct {
 }
 
 type OccurrenceDataItem struct {
-	ID          int    `json:"id"`
-	Environment string `json:"environment"`
-	ProjectID   int    `json:"project_id"`
+	ID               int     `json:"id"`
+	Environment      string  `json:"environment"`
+	ProjectID        int     `json:"project_id"`
+	Title            *string `json:"title"`
+	TotalOccurrences *int    `json:"total_occurrences"`
 }
 
 type OccurrenceData struct {
@@ -80,9 +103,19 @@ func (o *Occurrence) Tags() map[string]string {
 }
 
 func (o *Occurrence) Fields() map[string]interface{} {
-	return map[string]interface{}{
+	fields := map[string]interface{}{
 		"id": o.Data.Item.ID,
 	}
+	if o.Data.Item.Title != nil {
+		fields["title"] = *o.Data.Item.Title
+	}
+	if o.Data.Item.TotalOccurrences != nil {
+		fields["total_occurrences"] = *o.Data.Item.TotalOccurrences
+	}
+	if v, found := levelValues[o.Data.Occurrence.Level]; found {
+		fields["level_value"] = v
+	}
+	return fields
 }
 
 type DeployDataDeploy struct {
@@ -113,3 +146,36 @@ func (ni *Deploy) Fields() map[string]interface{} {
 		"id": ni.Data.Deploy.ID,
 	}
 }
+
+// ReactivatedItem is sent when a resolved item occurs again and carries the
+// same data as a new item
+type ReactivatedItem struct {
+	NewItem
+}
+
+// ResolvedItem is sent when an item is marked as resolved and carries the
+// same data as a new item
+type ResolvedItem struct {
+	NewItem
+}
+
+type TestData struct {
+	Message string `json:"message"`
+}
+
+type Test struct {
+	EventName string   `json:"event_name"`
+	Data      TestData `json:"data"`
+}
+
+func (t *Test) Tags() map[string]string {
+	return map[string]string{
+		"event": t.EventName,
+	}
+}
+
+func (t *Test) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"message": t.Data.Message,
+	}
+}
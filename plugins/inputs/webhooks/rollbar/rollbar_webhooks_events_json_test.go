@@ -152,6 +152,96 @@ func DeployJSON() string {
     }`
 }
 
+func ReactivatedItemJSON() string {
+	return `
+	{
+	  "event_name": "reactivated_item",
+	  "data": {
+		"item": {
+		  "public_item_id": null,
+		  "integrations_data": {},
+		  "last_activated_timestamp": 1382655421,
+		  "unique_occurrences": null,
+		  "id": 272716944,
+		  "environment": "production",
+		  "title": "testing aobg98wrwe",
+		  "last_occurrence_id": 481761639,
+		  "last_occurrence_timestamp": 1382655421,
+		  "platform": 0,
+		  "first_occurrence_timestamp": 1382655421,
+		  "project_id": 90,
+		  "resolved_in_version": null,
+		  "status": 1,
+		  "hash": "c595b2ae0af9b397bb6bdafd57104ac4d5f6b382",
+		  "last_occurrence": {
+			"uuid": "d2036647-e0b7-4cad-bc98-934831b9b6d1",
+			"language": "python",
+			"level": "error",
+			"timestamp": 1382655421,
+			"environment": "production",
+			"framework": "unknown"
+		  },
+		  "framework": 0,
+		  "total_occurrences": 5,
+		  "level": 40,
+		  "counter": 4,
+		  "first_occurrence_id": 481761639,
+		  "activating_occurrence_id": 481761639
+		}
+	  }
+	}`
+}
+
+func ResolvedItemJSON() string {
+	return `
+	{
+	  "event_name": "resolved_item",
+	  "data": {
+		"item": {
+		  "public_item_id": null,
+		  "integrations_data": {},
+		  "last_activated_timestamp": 1382655421,
+		  "unique_occurrences": null,
+		  "id": 272716944,
+		  "environment": "production",
+		  "title": "testing aobg98wrwe",
+		  "last_occurrence_id": 481761639,
+		  "last_occurrence_timestamp": 1382655421,
+		  "platform": 0,
+		  "first_occurrence_timestamp": 1382655421,
+		  "project_id": 90,
+		  "resolved_in_version": "e4b9b7db860b2e5ac799f8c06b9498b71ab270bb",
+		  "status": 2,
+		  "hash": "c595b2ae0af9b397bb6bdafd57104ac4d5f6b382",
+		  "last_occurrence": {
+			"uuid": "d2036647-e0b7-4cad-bc98-934831b9b6d1",
+			"language": "python",
+			"level": "error",
+			"timestamp": 1382655421,
+			"environment": "production",
+			"framework": "unknown"
+		  },
+		  "framework": 0,
+		  "total_occurrences": 5,
+		  "level": 40,
+		  "counter": 4,
+		  "first_occurrence_id": 481761639,
+		  "activating_occurrence_id": 481761639
+		}
+	  }
+	}`
+}
+
+func PingEventJSON() string {
+	return `
+    {
+      "event_name": "test",
+      "data": {
+        "message": "This is a test payload from Rollbar. If you got this, it works!"
+      }
+    }`
+}
+
 func UnknowJSON() string {
 	return `
     {
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -28,7 +30,10 @@ func TestNewItem(t *testing.T) {
 	}
 
 	fields := map[string]interface{}{
-		"id": 272716944,
+		"id":                272716944,
+		"title":             "testing aobg98wrwe",
+		"total_occurrences": 1,
+		"level_value":       40,
 	}
 
 	tags := map[string]string{
@@ -42,6 +47,15 @@ func TestNewItem(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "rollbar_webhooks", fields, tags)
 }
 
+func TestNewItemWithoutOptionalFields(t *testing.T) {
+	var acc testutil.Accumulator
+	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
+	resp := postWebhooks(rb, `{"event_name": "new_item", "data": {"item": {"id": 42, "environment": "staging", "project_id": 90}}}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, map[string]interface{}{"id": 42}, acc.Metrics[0].Fields)
+}
+
 func TestOccurrence(t *testing.T) {
 	var acc testutil.Accumulator
 	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
@@ -51,7 +65,10 @@ func TestOccurrence(t *testing.T) {
 	}
 
 	fields := map[string]interface{}{
-		"id": 402860571,
+		"id":                402860571,
+		"title":             "Exception: test exception",
+		"total_occurrences": 8,
+		"level_value":       40,
 	}
 
 	tags := map[string]string{
@@ -65,6 +82,38 @@ func TestOccurrence(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "rollbar_webhooks", fields, tags)
 }
 
+func TestLevelValue(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected interface{}
+	}{
+		{level: "critical", expected: 50},
+		{level: "error", expected: 40},
+		{level: "warning", expected: 30},
+		{level: "info", expected: 20},
+		{level: "debug", expected: 10},
+		{level: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var acc testutil.Accumulator
+			rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
+			body := `{"event_name": "occurrence", "data": {"item": {"id": 42}, "occurrence": {"level": "` + tt.level + `"}}}`
+			resp := postWebhooks(rb, body)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Len(t, acc.Metrics, 1)
+			require.Equal(t, tt.level, acc.Metrics[0].Tags["level"])
+			value, found := acc.Metrics[0].Fields["level_value"]
+			if tt.expected == nil {
+				require.False(t, found)
+				return
+			}
+			require.Equal(t, tt.expected, value)
+		})
+	}
+}
+
 func TestDeploy(t *testing.T) {
 	var acc testutil.Accumulator
 	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
@@ -86,10 +135,139 @@ func TestDeploy(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "rollbar_webhooks", fields, tags)
 }
 
+func TestReactivatedItem(t *testing.T) {
+	var acc testutil.Accumulator
+	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
+	resp := postWebhooks(rb, ReactivatedItemJSON())
+	if resp.Code != http.StatusOK {
+		t.Errorf("POST reactivated_item returned HTTP status code %v.\nExpected %v", resp.Code, http.StatusOK)
+	}
+
+	fields := map[string]interface{}{
+		"id":                272716944,
+		"title":             "testing aobg98wrwe",
+		"total_occurrences": 5,
+		"level_value":       40,
+	}
+
+	tags := map[string]string{
+		"event":       "reactivated_item",
+		"environment": "production",
+		"project_id":  "90",
+		"language":    "python",
+		"level":       "error",
+	}
+
+	acc.AssertContainsTaggedFields(t, "rollbar_webhooks", fields, tags)
+}
+
+func TestResolvedItem(t *testing.T) {
+	var acc testutil.Accumulator
+	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
+	resp := postWebhooks(rb, ResolvedItemJSON())
+	if resp.Code != http.StatusOK {
+		t.Errorf("POST resolved_item returned HTTP status code %v.\nExpected %v", resp.Code, http.StatusOK)
+	}
+
+	fields := map[string]interface{}{
+		"id":                272716944,
+		"title":             "testing aobg98wrwe",
+		"total_occurrences": 5,
+		"level_value":       40,
+	}
+
+	tags := map[string]string{
+		"event":       "resolved_item",
+		"environment": "production",
+		"project_id":  "90",
+		"language":    "python",
+		"level":       "error",
+	}
+
+	acc.AssertContainsTaggedFields(t, "rollbar_webhooks", fields, tags)
+}
+
+func TestTestEvent(t *testing.T) {
+	var acc testutil.Accumulator
+	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc}
+	resp := postWebhooks(rb, PingEventJSON())
+	if resp.Code != http.StatusOK {
+		t.Errorf("POST test returned HTTP status code %v.\nExpected %v", resp.Code, http.StatusOK)
+	}
+
+	fields := map[string]interface{}{
+		"message": "This is a test payload from Rollbar. If you got this, it works!",
+	}
+
+	tags := map[string]string{
+		"event": "test",
+	}
+
+	acc.AssertContainsTaggedFields(t, "rollbar_webhooks", fields, tags)
+}
+
+func TestAccessToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		header   string
+		expected int
+	}{
+		{
+			name:     "no token configured",
+			header:   "whatever",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "matching token",
+			token:    "secret",
+			header:   "secret",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "wrong token",
+			token:    "secret",
+			header:   "guessed",
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "missing token",
+			token:    "secret",
+			expected: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acc testutil.Accumulator
+			rb := &RollbarWebhook{Path: "/rollbar", AccessToken: tt.token, acc: &acc}
+
+			req, err := http.NewRequest("POST", "/", strings.NewReader(DeployJSON()))
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set("X-Rollbar-Access-Token", tt.header)
+			}
+			w := httptest.NewRecorder()
+			rb.eventHandler(w, req)
+
+			require.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusOK {
+				require.Len(t, acc.Metrics, 1)
+			} else {
+				require.Empty(t, acc.Metrics)
+			}
+		})
+	}
+}
+
 func TestUnknowItem(t *testing.T) {
-	rb := &RollbarWebhook{Path: "/rollbar"}
+	var acc testutil.Accumulator
+	rb := &RollbarWebhook{Path: "/rollbar", acc: &acc, log: testutil.Logger{}}
 	resp := postWebhooks(rb, UnknowJSON())
 	if resp.Code != http.StatusOK {
 		t.Errorf("POST unknow returned HTTP status code %v.\nExpected %v", resp.Code, http.StatusOK)
 	}
+	if acc.NMetrics() != 0 {
+		t.Errorf("POST unknow added %d metrics.\nExpected none", acc.NMetrics())
+	}
 }
@@ -1,6 +1,7 @@
 package influxdb_v2
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -14,15 +15,32 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
 	"golang.org/x/net/http2"
 )
 
+var (
+	writePaths = map[string]string{
+		"v2": "/api/v2/write",
+		"v3": "/api/v3/write_lp",
+	}
+
+	v3Precisions = map[string]string{
+		"ns": "nanosecond",
+		"us": "microsecond",
+		"ms": "millisecond",
+		"s":  "second",
+	}
+)
+
 type APIError struct {
 	StatusCode  int
 	Title       string
@@ -36,49 +54,112 @@ func (e APIError) Error() string {
 	return e.Title
 }
 
+// RetryError is returned if the server requested to back off and the given
+// time has to pass before the next write.
+type RetryError struct {
+	RetryAfter time.Duration
+}
+
+func (e RetryError) Error() string {
+	return fmt.Sprintf("retry time has not elapsed, retrying in %s", e.RetryAfter)
+}
+
 const (
 	defaultRequestTimeout           = time.Second * 5
 	defaultMaxWaitSeconds           = 60
 	defaultMaxWaitRetryAfterSeconds = 10 * 60
+	defaultStreamResetRetryDelay    = 100 * time.Millisecond
+	defaultMaxErrorResponseSize     = 1024 * 1024
 )
 
 type HTTPConfig struct {
-	URL              *url.URL
-	LocalAddr        *net.TCPAddr
-	Token            config.Secret
-	Organization     string
-	Bucket           string
-	BucketTag        string
-	ExcludeBucketTag bool
-	Timeout          time.Duration
-	Headers          map[string]string
-	Proxy            *url.URL
-	UserAgent        string
-	ContentEncoding  string
-	PingTimeout      config.Duration
-	ReadIdleTimeout  config.Duration
-	TLSConfig        *tls.Config
+	URL               *url.URL
+	LocalAddr         *net.TCPAddr
+	Token             config.Secret
+	Organization      string
+	Bucket            string
+	BucketTag         string
+	ExcludeBucketTag  bool
+	Timeout           time.Duration
+	Headers           map[string]string
+	Proxy             *url.URL
+	UserAgent         string
+	ContentEncoding   string
+	PingTimeout       config.Duration
+	ReadIdleTimeout   config.Duration
+	TLSConfig         *tls.Config
+	CorrelationHeader string
+	CorrelationTag    string
+
+	// APIVersion selects the write endpoint, either "v2" (default) using
+	// organization and bucket or "v3" using the bucket as database.
+	APIVersion string
+
+	// DeadLetter receives the metrics permanently rejected by the server as
+	// line protocol. Nil disables the dead-letter output.
+	DeadLetter io.Writer
+
+	// ContentEncodingLevel is the gzip compression level between 1 and 9 with
+	// zero using the default level.
+	ContentEncodingLevel int
+
+	// Precision of the timestamps sent to the server, one of "ns", "us", "ms"
+	// or "s". The serializer must use the same precision.
+	Precision string
+
+	// DisableSplitOnTooLarge returns the error for requests rejected as too
+	// large instead of retrying the write with the batch split in half.
+	DisableSplitOnTooLarge bool
+
+	// DeferTokenResolution allows to create the client if the token cannot
+	// be resolved and retries resolving the token when writing.
+	DeferTokenResolution bool
+
+	// LatencyWarningThreshold enables warnings for writes taking longer than
+	// the given duration.
+	LatencyWarningThreshold time.Duration
 
 	Serializer *influx.Serializer
 	Log        telegraf.Logger
 }
 
+type bytesWrittenStats struct {
+	wire selfstat.Stat
+	raw  selfstat.Stat
+}
+
 type httpClient struct {
-	ContentEncoding  string
-	Timeout          time.Duration
-	Headers          map[string]string
-	Organization     string
-	Bucket           string
-	BucketTag        string
-	ExcludeBucketTag bool
-
-	client     *http.Client
-	serializer *influx.Serializer
-	url        *url.URL
-	params     url.Values
-	retryTime  time.Time
-	retryCount int
-	log        telegraf.Logger
+	ContentEncoding   string
+	Timeout           time.Duration
+	Headers           map[string]string
+	Organization      string
+	Bucket            string
+	BucketTag         string
+	ExcludeBucketTag  bool
+	CorrelationHeader string
+	CorrelationTag    string
+
+	client      *http.Client
+	serializer  *influx.Serializer
+	url         *url.URL
+	params      url.Values
+	bucketParam string
+	writePath   string
+	retryTime   time.Time
+	retryCount  int
+	token       config.Secret
+	log         telegraf.Logger
+
+	disableSplit bool
+	gzipLevel    int
+	deadLetter   io.Writer
+
+	latencyThreshold time.Duration
+	slowWrites       selfstat.Stat
+	slow             bool
+
+	statsURL     string
+	bytesWritten map[string]bytesWrittenStats
 }
 
 func NewHTTPClient(cfg *HTTPConfig) (*httpClient, error) {
@@ -99,12 +180,16 @@ func NewHTTPClient(cfg *HTTPConfig) (*httpClient, error) {
 	var headers = make(map[string]string, len(cfg.Headers)+2)
 	headers["User-Agent"] = userAgent
 
+	// The token is resolved for each request, so only check its availability
 	token, err := cfg.Token.Get()
 	if err != nil {
-		return nil, fmt.Errorf("getting token failed: %w", err)
+		if !cfg.DeferTokenResolution {
+			return nil, fmt.Errorf("getting token failed: %w", err)
+		}
+		cfg.Log.Warnf("Getting token failed, deferring resolution to first write: %v", err)
+	} else {
+		token.Destroy()
 	}
-	headers["Authorization"] = "Token " + token.String()
-	token.Destroy()
 	for k, v := range cfg.Headers {
 		headers[k] = v
 	}
@@ -158,10 +243,18 @@ func NewHTTPClient(cfg *HTTPConfig) (*httpClient, error) {
 		return nil, fmt.Errorf("unsupported scheme %q", cfg.URL.Scheme)
 	}
 
-	preppedURL, params, err := prepareWriteURL(*cfg.URL, cfg.Organization)
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = "v2"
+	}
+	preppedURL, params, err := prepareWriteURL(*cfg.URL, cfg.Organization, cfg.Precision, apiVersion)
 	if err != nil {
 		return nil, err
 	}
+	bucketParam := "bucket"
+	if apiVersion == "v3" {
+		bucketParam = "db"
+	}
 
 	client := &httpClient{
 		serializer: serializer,
@@ -169,16 +262,30 @@ func NewHTTPClient(cfg *HTTPConfig) (*httpClient, error) {
 			Timeout:   timeout,
 			Transport: transport,
 		},
-		url:              preppedURL,
-		params:           params,
-		ContentEncoding:  cfg.ContentEncoding,
-		Timeout:          timeout,
-		Headers:          headers,
-		Organization:     cfg.Organization,
-		Bucket:           cfg.Bucket,
-		BucketTag:        cfg.BucketTag,
-		ExcludeBucketTag: cfg.ExcludeBucketTag,
-		log:              cfg.Log,
+		url:               preppedURL,
+		params:            params,
+		bucketParam:       bucketParam,
+		writePath:         writePaths[apiVersion],
+		ContentEncoding:   cfg.ContentEncoding,
+		Timeout:           timeout,
+		Headers:           headers,
+		Organization:      cfg.Organization,
+		Bucket:            cfg.Bucket,
+		BucketTag:         cfg.BucketTag,
+		ExcludeBucketTag:  cfg.ExcludeBucketTag,
+		CorrelationHeader: cfg.CorrelationHeader,
+		CorrelationTag:    cfg.CorrelationTag,
+		token:             cfg.Token,
+		log:               cfg.Log,
+		latencyThreshold:  cfg.LatencyWarningThreshold,
+		disableSplit:      cfg.DisableSplitOnTooLarge,
+		gzipLevel:         cfg.ContentEncodingLevel,
+		deadLetter:        cfg.DeadLetter,
+		statsURL:          cfg.URL.Redacted(),
+		bytesWritten:      make(map[string]bytesWrittenStats),
+	}
+	if cfg.LatencyWarningThreshold > 0 {
+		client.slowWrites = selfstat.Register("influxdb_v2", "slow_writes", map[string]string{"url": client.statsURL})
 	}
 	return client, nil
 }
@@ -198,16 +305,16 @@ type genericRespError struct {
 func (g genericRespError) Error() string {
 	errString := fmt.Sprintf("%s: %s", g.Code, g.Message)
 	if g.Line != nil {
-		return fmt.Sprintf("%s - line[%d]", errString, g.Line)
+		return fmt.Sprintf("%s - line[%d]", errString, *g.Line)
 	} else if g.MaxLength != nil {
-		return fmt.Sprintf("%s - maxlen[%d]", errString, g.MaxLength)
+		return fmt.Sprintf("%s - maxlen[%d]", errString, *g.MaxLength)
 	}
 	return errString
 }
 
 func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error {
-	if c.retryTime.After(time.Now()) {
-		return errors.New("retry time has not elapsed")
+	if wait := c.RetryAfter(); wait > 0 {
+		return &RetryError{RetryAfter: wait}
 	}
 
 	batches := make(map[string][]telegraf.Metric)
@@ -216,7 +323,7 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 		if err != nil {
 			var apiErr *APIError
 			if errors.As(err, &apiErr) {
-				if apiErr.StatusCode == http.StatusRequestEntityTooLarge {
+				if apiErr.StatusCode == http.StatusRequestEntityTooLarge && !c.disableSplit {
 					return c.splitAndWriteBatch(ctx, c.Bucket, metrics)
 				}
 			}
@@ -249,7 +356,7 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 			if err != nil {
 				var apiErr *APIError
 				if errors.As(err, &apiErr) {
-					if apiErr.StatusCode == http.StatusRequestEntityTooLarge {
+					if apiErr.StatusCode == http.StatusRequestEntityTooLarge && !c.disableSplit {
 						return c.splitAndWriteBatch(ctx, c.Bucket, metrics)
 					}
 				}
@@ -261,6 +368,51 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 	return nil
 }
 
+// RetryAfter returns the time to wait before the next write or zero if the
+// client is not backing off.
+func (c *httpClient) RetryAfter() time.Duration {
+	return max(time.Until(c.retryTime), 0)
+}
+
+// Ping checks the server's health endpoint and returns an error if the server
+// is unreachable or does not report to be healthy.
+func (c *httpClient) Ping(ctx context.Context) error {
+	loc := *c.url
+	loc.Path = path.Join(strings.TrimSuffix(loc.Path, c.writePath), "/health")
+	loc.RawQuery = ""
+
+	req, err := http.NewRequestWithContext(ctx, "GET", loc.String(), nil)
+	if err != nil {
+		return err
+	}
+	c.addHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var health struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	// The body is informational only, so ignore decoding errors
+	_ = json.NewDecoder(io.LimitReader(resp.Body, defaultMaxErrorResponseSize)).Decode(&health)
+
+	if resp.StatusCode != http.StatusOK {
+		if health.Message != "" {
+			return fmt.Errorf("server returned %s: %s", resp.Status, health.Message)
+		}
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	if health.Status != "" && health.Status != "pass" {
+		return fmt.Errorf("server reported status %q: %s", health.Status, health.Message)
+	}
+
+	return nil
+}
+
 func (c *httpClient) splitAndWriteBatch(ctx context.Context, bucket string, metrics []telegraf.Metric) error {
 	c.log.Warnf("Retrying write after splitting metric payload in half to reduce batch size")
 	midpoint := len(metrics) / 2
@@ -273,20 +425,29 @@ func (c *httpClient) splitAndWriteBatch(ctx context.Context, bucket string, metr
 }
 
 func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []telegraf.Metric) error {
-	reader := c.requestBodyReader(metrics)
-	defer reader.Close()
-
-	req, err := c.makeWriteRequest(makeWriteURL(*c.url, c.params, bucket), reader)
-	if err != nil {
-		return err
+	start := time.Now()
+	resp, counter, err := c.sendBatch(ctx, bucket, metrics)
+	if err != nil && isHTTP2StreamReset(err) {
+		// A reset of the HTTP/2 stream, e.g. due to the server sending GOAWAY,
+		// is transient so retry once on a new stream before failing the write.
+		c.log.Debugf("HTTP/2 stream reset while writing to %s, retrying: %v", bucket, err)
+		if err := internal.SleepContext(ctx, defaultStreamResetRetryDelay); err != nil {
+			return err
+		}
+		resp, counter, err = c.sendBatch(ctx, bucket, metrics)
 	}
-
-	resp, err := c.client.Do(req.WithContext(ctx))
+	latency := time.Since(start)
 	if err != nil {
+		// Failed writes, e.g. timeouts, count as slow but do not indicate
+		// a recovery of the latency if they fail fast.
+		if latency >= c.latencyThreshold {
+			c.checkLatency(bucket, latency)
+		}
 		internal.OnClientError(c.client, err)
 		return err
 	}
 	defer resp.Body.Close()
+	c.checkLatency(bucket, latency)
 
 	switch resp.StatusCode {
 	case
@@ -300,11 +461,14 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 		http.StatusMultiStatus,
 		http.StatusAlreadyReported:
 		c.retryCount = 0
+		c.recordBytesWritten(bucket, counter)
 		return nil
 	}
 
 	writeResp := &genericRespError{}
-	err = json.NewDecoder(resp.Body).Decode(writeResp)
+	// Limit the size of the error body to not exhaust memory on pathological
+	// responses, e.g. by misbehaving proxies
+	err = json.NewDecoder(io.LimitReader(resp.Body, defaultMaxErrorResponseSize)).Decode(writeResp)
 	desc := writeResp.Error()
 	if err != nil {
 		desc = resp.Status
@@ -327,7 +491,15 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 		// Clients should *not* repeat the request and the metrics should be dropped.
 		http.StatusUnprocessableEntity,
 		http.StatusNotAcceptable:
+		// In case the server tells us the offending line, only drop the
+		// corresponding metric and deliver the remaining ones.
+		if idx := c.metricAtLine(metrics, writeResp.Line); idx >= 0 && len(metrics) > 1 {
+			c.log.Errorf("Failed to write metric %d of the batch to %s (will be dropped: %s): %s", idx, bucket, resp.Status, desc)
+			c.writeDeadLetter(bucket, desc, metrics[idx:idx+1])
+			return c.writeWithout(ctx, bucket, metrics, idx)
+		}
 		c.log.Errorf("Failed to write metric to %s (will be dropped: %s): %s\n", bucket, resp.Status, desc)
+		c.writeDeadLetter(bucket, desc, metrics)
 		return nil
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("failed to write metric to %s (%s): %s", bucket, resp.Status, desc)
@@ -347,6 +519,7 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 	// retrying will not make the request magically work.
 	if len(resp.Status) > 0 && resp.Status[0] == '4' {
 		c.log.Errorf("Failed to write metric to %s (will be dropped: %s): %s\n", bucket, resp.Status, desc)
+		c.writeDeadLetter(bucket, desc, metrics)
 		return nil
 	}
 
@@ -363,6 +536,197 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 	}
 }
 
+// writeDeadLetter appends the rejected metrics as line protocol to the
+// dead-letter file, preceded by a comment containing the server's error.
+func (c *httpClient) writeDeadLetter(bucket, reason string, metrics []telegraf.Metric) {
+	if c.deadLetter == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s bucket=%q: %s\n", time.Now().UTC().Format(time.RFC3339), bucket, strings.ReplaceAll(reason, "\n", " "))
+	for _, m := range metrics {
+		line, err := c.serializer.Serialize(m)
+		if err != nil {
+			continue
+		}
+		buf.Write(line)
+	}
+
+	if _, err := c.deadLetter.Write(buf.Bytes()); err != nil {
+		c.log.Errorf("Writing rejected metrics to dead-letter file failed: %v", err)
+	}
+}
+
+// metricAtLine returns the index of the metric serialized to the given
+// one-based line of the request body or -1 if the line cannot be mapped to a
+// metric. Metrics failing to serialize are skipped by the body reader so they
+// do not occupy a line.
+func (c *httpClient) metricAtLine(metrics []telegraf.Metric, line *int32) int {
+	if line == nil || *line < 1 {
+		return -1
+	}
+
+	var lines int
+	for i, m := range metrics {
+		buf, err := c.serializer.Serialize(m)
+		if err != nil {
+			continue
+		}
+		lines += bytes.Count(buf, []byte("\n"))
+		if lines >= int(*line) {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeWithout writes the batch without the metric at the given index. The
+// parts before and after the dropped metric are written separately to allow
+// isolating further malformed metrics in those parts. As partial writes cannot
+// be reported to the model, an error in a later part causes already written
+// parts to be sent again when the batch is retried.
+func (c *httpClient) writeWithout(ctx context.Context, bucket string, metrics []telegraf.Metric, idx int) error {
+	if idx > 0 {
+		if err := c.writeBatch(ctx, bucket, metrics[:idx]); err != nil {
+			return err
+		}
+	}
+	if idx < len(metrics)-1 {
+		return c.writeBatch(ctx, bucket, metrics[idx+1:])
+	}
+	return nil
+}
+
+// checkLatency warns about slow writes. To not flood the log, the warning is
+// only issued when crossing the threshold and not for subsequent slow writes.
+func (c *httpClient) checkLatency(bucket string, latency time.Duration) {
+	if c.latencyThreshold <= 0 {
+		return
+	}
+
+	if latency < c.latencyThreshold {
+		if c.slow {
+			c.log.Infof("Write latency to %s back to normal (%s)", bucket, latency)
+			c.slow = false
+		}
+		return
+	}
+
+	c.slowWrites.Incr(1)
+	if !c.slow {
+		c.log.Warnf("Write to %s took %s exceeding the threshold of %s", bucket, latency, c.latencyThreshold)
+		c.slow = true
+	}
+}
+
+// sendBatch serializes the metrics and sends them in a single write request.
+// The request body is closed by the transport once the request is done.
+func (c *httpClient) sendBatch(ctx context.Context, bucket string, metrics []telegraf.Metric) (*http.Response, *bodyCounter, error) {
+	counter := &bodyCounter{}
+	counter.raw.ReadCloser = io.NopCloser(influx.NewReader(metrics, c.serializer))
+	counter.wire.ReadCloser = c.requestBodyReader(&counter.raw)
+	reader := &counter.wire
+
+	req, err := c.makeWriteRequest(makeWriteURL(*c.url, c.params, c.bucketParam, bucket), reader)
+	if err != nil {
+		reader.Close()
+		return nil, nil, err
+	}
+
+	if c.CorrelationHeader != "" {
+		id, err := c.correlationID(metrics)
+		if err != nil {
+			reader.Close()
+			return nil, nil, fmt.Errorf("creating correlation ID failed: %w", err)
+		}
+		req.Header.Set(c.CorrelationHeader, id)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	return resp, counter, err
+}
+
+// countingReader counts the bytes read from the underlying reader. The count
+// is atomic as the transport might read the body in the background.
+type countingReader struct {
+	io.ReadCloser
+	count atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+// bodyCounter counts the bytes of a request body before and after encoding
+type bodyCounter struct {
+	raw  countingReader
+	wire countingReader
+}
+
+// recordBytesWritten adds the bytes of a successful write to the statistics
+// of the bucket.
+func (c *httpClient) recordBytesWritten(bucket string, counter *bodyCounter) {
+	if c.BucketTag == "" {
+		bucket = ""
+	}
+
+	stats, found := c.bytesWritten[bucket]
+	if !found {
+		tags := map[string]string{"url": c.statsURL}
+		if c.BucketTag != "" {
+			tags["bucket"] = bucket
+		}
+		stats = bytesWrittenStats{
+			wire: selfstat.Register("influxdb_v2", "bytes_written", tags),
+			raw:  selfstat.Register("influxdb_v2", "bytes_written_uncompressed", tags),
+		}
+		c.bytesWritten[bucket] = stats
+	}
+	stats.wire.Incr(counter.wire.count.Load())
+	stats.raw.Incr(counter.raw.count.Load())
+}
+
+// correlationID returns the value of the correlation tag of the first metric
+// carrying the tag or a random UUID if no such metric exists.
+func (c *httpClient) correlationID(metrics []telegraf.Metric) (string, error) {
+	if c.CorrelationTag != "" {
+		for _, m := range metrics {
+			if id, found := m.GetTag(c.CorrelationTag); found {
+				return id, nil
+			}
+		}
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// isHTTP2StreamReset checks if the error is caused by the server resetting
+// the HTTP/2 stream or closing the connection using GOAWAY.
+func isHTTP2StreamReset(err error) bool {
+	var streamErr http2.StreamError
+	if errors.As(err, &streamErr) {
+		return true
+	}
+	var goAwayErr http2.GoAwayError
+	if errors.As(err, &goAwayErr) {
+		return true
+	}
+
+	// The standard library uses a bundled copy of the HTTP/2 implementation
+	// so we cannot match the error types for the default transport.
+	msg := err.Error()
+	return strings.Contains(msg, "http2: stream closed") ||
+		strings.Contains(msg, "http2: server sent GOAWAY") ||
+		strings.Contains(msg, "stream error: stream ID")
+}
+
 // retryDuration takes the longer of the Retry-After header and our own back-off calculation
 func (c *httpClient) getRetryDuration(headers http.Header) time.Duration {
 	// basic exponential backoff (x^2)/40 (denominator to widen the slope)
@@ -377,8 +741,13 @@ func (c *httpClient) getRetryDuration(headers http.Header) time.Duration {
 		var err error
 		retryAfterHeader, err = strconv.ParseFloat(retryAfterHeaderString, 64)
 		if err != nil {
-			// there was a value but we couldn't parse it? guess minimum 10 sec
-			retryAfterHeader = 10
+			// the header might also contain a HTTP-date according to RFC 7231
+			if ts, err := http.ParseTime(retryAfterHeaderString); err == nil {
+				retryAfterHeader = math.Max(time.Until(ts).Seconds(), 0)
+			} else {
+				// there was a value but we couldn't parse it? guess minimum 10 sec
+				retryAfterHeader = 10
+			}
 		}
 		// protect against excessively large retry-after
 		retryAfterHeader = math.Min(retryAfterHeader, defaultMaxWaitRetryAfterSeconds)
@@ -397,6 +766,19 @@ func (c *httpClient) makeWriteRequest(address string, body io.Reader) (*http.Req
 	}
 
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	// Resolve the token for each request to pick up rotated secrets. On
+	// failure, the next attempt is delayed using the same backoff as for
+	// overloaded servers.
+	token, err := c.token.Get()
+	if err != nil {
+		c.retryCount++
+		c.retryTime = time.Now().Add(c.getRetryDuration(http.Header{}))
+		return nil, fmt.Errorf("getting token failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+token.String())
+	token.Destroy()
+
 	c.addHeaders(req)
 
 	if c.ContentEncoding == "gzip" {
@@ -408,10 +790,11 @@ func (c *httpClient) makeWriteRequest(address string, body io.Reader) (*http.Req
 
 // requestBodyReader warp io.Reader from influx.NewReader to io.ReadCloser, which is useful to fast close the write
 // side of the connection in case of error
-func (c *httpClient) requestBodyReader(metrics []telegraf.Metric) io.ReadCloser {
-	reader := influx.NewReader(metrics, c.serializer)
-
+func (c *httpClient) requestBodyReader(reader io.Reader) io.ReadCloser {
 	if c.ContentEncoding == "gzip" {
+		if c.gzipLevel > 0 {
+			return internal.CompressWithGzipLevel(reader, c.gzipLevel)
+		}
 		return internal.CompressWithGzip(reader)
 	}
 
@@ -428,26 +811,42 @@ func (c *httpClient) addHeaders(req *http.Request) {
 	}
 }
 
-func makeWriteURL(loc url.URL, params url.Values, bucket string) string {
-	params.Set("bucket", bucket)
+func makeWriteURL(loc url.URL, params url.Values, bucketParam, bucket string) string {
+	params.Set(bucketParam, bucket)
 	loc.RawQuery = params.Encode()
 	return loc.String()
 }
 
-func prepareWriteURL(loc url.URL, org string) (*url.URL, url.Values, error) {
+func prepareWriteURL(loc url.URL, org, precision, apiVersion string) (*url.URL, url.Values, error) {
+	writePath, found := writePaths[apiVersion]
+	if !found {
+		return nil, nil, fmt.Errorf("unsupported API version: %q", apiVersion)
+	}
+
 	switch loc.Scheme {
 	case "unix":
 		loc.Scheme = "http"
 		loc.Host = "127.0.0.1"
-		loc.Path = "/api/v2/write"
+		loc.Path = writePath
 	case "http", "https":
-		loc.Path = path.Join(loc.Path, "/api/v2/write")
+		loc.Path = path.Join(loc.Path, writePath)
 	default:
 		return nil, nil, fmt.Errorf("unsupported scheme: %q", loc.Scheme)
 	}
 
 	params := loc.Query()
-	params.Set("org", org)
+	switch apiVersion {
+	case "v3":
+		// The v3 API has no organizations and uses the long precision names
+		if precision != "" {
+			params.Set("precision", v3Precisions[precision])
+		}
+	default:
+		params.Set("org", org)
+		if precision != "" {
+			params.Set("precision", precision)
+		}
+	}
 
 	return &loc, params, nil
 }
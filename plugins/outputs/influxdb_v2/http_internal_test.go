@@ -1,6 +1,7 @@
 package influxdb_v2
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
 )
 
 func genURL(u string) *url.URL {
@@ -23,6 +25,8 @@ func TestMakeWriteURL(t *testing.T) {
 		act string
 		bkt string
 		org string
+		prc string
+		api string
 	}{
 		{
 			url: genURL("http://localhost:9999"),
@@ -42,6 +46,32 @@ func TestMakeWriteURL(t *testing.T) {
 			bkt: "telegraf2",
 			org: "influx2",
 		},
+		{
+			url: genURL("http://localhost:9999"),
+			act: "http://localhost:9999/api/v2/write?bucket=telegraf3&org=influx3&precision=s",
+			bkt: "telegraf3",
+			org: "influx3",
+			prc: "s",
+		},
+		{
+			url: genURL("http://localhost:9999"),
+			act: "http://localhost:9999/api/v3/write_lp?db=telegraf4&precision=millisecond",
+			bkt: "telegraf4",
+			org: "influx4",
+			prc: "ms",
+			api: "v3",
+		},
+		{
+			url: genURL("unix://var/run/influxd.sock"),
+			act: "http://127.0.0.1/api/v3/write_lp?db=telegraf5",
+			bkt: "telegraf5",
+			api: "v3",
+		},
+		{
+			err: true,
+			url: genURL("http://localhost:9999"),
+			api: "v1",
+		},
 		{
 			err: true,
 			url: genURL("udp://localhost:9999"),
@@ -49,7 +79,14 @@ func TestMakeWriteURL(t *testing.T) {
 	}
 
 	for i := range tests {
-		rURL, params, err := prepareWriteURL(*tests[i].url, tests[i].org)
+		api, bucketParam := "v2", "bucket"
+		if tests[i].api != "" {
+			api = tests[i].api
+		}
+		if api == "v3" {
+			bucketParam = "db"
+		}
+		rURL, params, err := prepareWriteURL(*tests[i].url, tests[i].org, tests[i].prc, api)
 		if !tests[i].err {
 			require.NoError(t, err)
 		} else {
@@ -58,7 +95,7 @@ func TestMakeWriteURL(t *testing.T) {
 		}
 		if err == nil {
 			for j := 0; j < 2; j++ {
-				require.Equal(t, tests[i].act, makeWriteURL(*rURL, params, tests[i].bkt))
+				require.Equal(t, tests[i].act, makeWriteURL(*rURL, params, bucketParam, tests[i].bkt))
 			}
 		}
 	}
@@ -114,10 +151,88 @@ func TestExponentialBackoffCalculationWithRetryAfter(t *testing.T) {
 	}
 }
 
+func TestExponentialBackoffCalculationWithRetryAfterDate(t *testing.T) {
+	c := &httpClient{}
+	now := time.Now()
+	tests := []struct {
+		name       string
+		retryAfter string
+		min        time.Duration
+		max        time.Duration
+	}{
+		{
+			name:       "future",
+			retryAfter: now.Add(2 * time.Minute).UTC().Format(http.TimeFormat),
+			min:        time.Minute,
+			max:        2 * time.Minute,
+		},
+		{
+			name:       "past",
+			retryAfter: now.Add(-time.Minute).UTC().Format(http.TimeFormat),
+			min:        0,
+			max:        0,
+		},
+		{
+			name:       "max hit",
+			retryAfter: now.Add(time.Hour).UTC().Format(http.TimeFormat),
+			min:        600 * time.Second,
+			max:        600 * time.Second,
+		},
+		{
+			name:       "invalid",
+			retryAfter: "tomorrow",
+			min:        10 * time.Second,
+			max:        10 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hdr := http.Header{}
+			hdr.Add("Retry-After", test.retryAfter)
+			actual := c.getRetryDuration(hdr)
+			require.GreaterOrEqual(t, actual, test.min)
+			require.LessOrEqual(t, actual, test.max)
+		})
+	}
+}
+
+func TestIsHTTP2StreamReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "stream error",
+			err:      fmt.Errorf("post failed: %w", http2.StreamError{StreamID: 1, Code: http2.ErrCodeCancel}),
+			expected: true,
+		},
+		{
+			name:     "goaway",
+			err:      &url.Error{Op: "Post", URL: "http://localhost", Err: http2.GoAwayError{ErrCode: http2.ErrCodeNo}},
+			expected: true,
+		},
+		{
+			name:     "bundled stream closed",
+			err:      errors.New("http2: stream closed"),
+			expected: true,
+		},
+		{
+			name: "connection refused",
+			err:  errors.New("dial tcp 127.0.0.1:8086: connect: connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isHTTP2StreamReset(tt.err))
+		})
+	}
+}
+
 var (
 	bucket         = "bkt"
 	org            = "org"
-	loc, params, _ = prepareWriteURL(*genURL("http://localhost:8086"), org)
+	loc, params, _ = prepareWriteURL(*genURL("http://localhost:8086"), org, "", "v2")
 )
 
 // goos: linux
@@ -146,7 +261,7 @@ func BenchmarkOldMakeWriteURL(b *testing.B) {
 func BenchmarkNewMakeWriteURL(b *testing.B) {
 	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
-		makeWriteURL(*loc, params, bucket)
+		makeWriteURL(*loc, params, "bucket", bucket)
 	}
 }
 
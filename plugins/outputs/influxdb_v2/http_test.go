@@ -2,10 +2,12 @@ package influxdb_v2_test
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
 	influxdb "github.com/influxdata/telegraf/plugins/outputs/influxdb_v2"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -280,3 +283,191 @@ func TestTooLargeWriteRetry(t *testing.T) {
 	err = client.Write(ctx, hugeMetrics)
 	require.Error(t, err)
 }
+
+func TestTooLargeWriteNoSplit(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}),
+	)
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	cfg := &influxdb.HTTPConfig{
+		URL:                    addr,
+		Bucket:                 "telegraf",
+		DisableSplitOnTooLarge: true,
+		Log:                    testutil.Logger{},
+	}
+
+	client, err := influxdb.NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 99.0}, time.Unix(0, 0)),
+	}
+
+	// The error must be returned directly without retrying split batches
+	err = client.Write(context.Background(), metrics)
+	var apiErr *influxdb.APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusRequestEntityTooLarge, apiErr.StatusCode)
+	require.Equal(t, 1, requests)
+}
+
+func TestWriteHugeErrorResponse(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			// Send an error message exceeding the size limit
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"code":"internal error","message":"%s"}`, strings.Repeat("x", 2*1024*1024))
+		}),
+	)
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	cfg := &influxdb.HTTPConfig{
+		URL:    addr,
+		Bucket: "telegraf",
+		Log:    testutil.Logger{},
+	}
+
+	client, err := influxdb.NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+	}
+
+	// The truncated body is no valid JSON so the status must be reported
+	err = client.Write(context.Background(), metrics)
+	var apiErr *influxdb.APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "500 Internal Server Error", apiErr.Description)
+}
+
+func TestWriteDropsMalformedMetricOnly(t *testing.T) {
+	var received []string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v2/write":
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				// Reject the batch and report the line of the metric the server
+				// does not accept
+				lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+				for i, line := range lines {
+					if strings.HasPrefix(line, "bad") {
+						w.WriteHeader(http.StatusBadRequest)
+						fmt.Fprintf(w, `{"code":"invalid","message":"unable to parse","line":%d}`, i+1)
+						return
+					}
+				}
+				received = append(received, lines...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}),
+	)
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	cfg := &influxdb.HTTPConfig{
+		URL:    addr,
+		Bucket: "telegraf",
+		Log:    testutil.Logger{},
+	}
+
+	client, err := influxdb.NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(1, 0)),
+		metric.New("bad", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(2, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(3, 0)),
+		metric.New("bad", map[string]string{}, map[string]interface{}{"value": 4}, time.Unix(4, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 5}, time.Unix(5, 0)),
+	}
+	require.NoError(t, client.Write(context.Background(), metrics))
+
+	expected := []string{
+		"cpu value=1i 1000000000",
+		"cpu value=3i 3000000000",
+		"cpu value=5i 5000000000",
+	}
+	require.Equal(t, expected, received)
+}
+
+func TestWriteDropsMalformedMetricRetryableError(t *testing.T) {
+	var requests int
+	var received []string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			requests++
+
+			lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+			switch requests {
+			case 1:
+				// Reject the whole batch due to the malformed second line
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code":"invalid","message":"unable to parse","line":2}`)
+			case 2:
+				// Accept the part before the malformed metric
+				received = append(received, lines...)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				// Fail the part after the malformed metric with a retryable error
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	cfg := &influxdb.HTTPConfig{
+		URL:    addr,
+		Bucket: "telegraf",
+		Log:    testutil.Logger{},
+	}
+
+	client, err := influxdb.NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(1, 0)),
+		metric.New("bad", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(2, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(3, 0)),
+	}
+
+	// The write must fail to retry the batch, even though the first part was
+	// already written and will be sent again on retry.
+	require.Error(t, client.Write(context.Background(), metrics))
+	require.Equal(t, 3, requests)
+	require.Equal(t, []string{"cpu value=1i 1000000000"}, received)
+}
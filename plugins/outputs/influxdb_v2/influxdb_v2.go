@@ -6,15 +6,20 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/rotate"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
@@ -27,37 +32,67 @@ var (
 	defaultURL = "http://localhost:8086"
 
 	ErrMissingURL = errors.New("missing URL")
+
+	precisions = map[string]time.Duration{
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+	}
 )
 
 type Client interface {
 	Write(context.Context, []telegraf.Metric) error
+	Ping(context.Context) error
+	RetryAfter() time.Duration
 
 	URL() string // for logging
 	Close()
 }
 
 type InfluxDB struct {
-	URLs             []string          `toml:"urls"`
-	LocalAddr        string            `toml:"local_address"`
-	Token            config.Secret     `toml:"token"`
-	Organization     string            `toml:"organization"`
-	Bucket           string            `toml:"bucket"`
-	BucketTag        string            `toml:"bucket_tag"`
-	ExcludeBucketTag bool              `toml:"exclude_bucket_tag"`
-	Timeout          config.Duration   `toml:"timeout"`
-	HTTPHeaders      map[string]string `toml:"http_headers"`
-	HTTPProxy        string            `toml:"http_proxy"`
-	UserAgent        string            `toml:"user_agent"`
-	ContentEncoding  string            `toml:"content_encoding"`
-	UintSupport      bool              `toml:"influx_uint_support"`
-	OmitTimestamp    bool              `toml:"influx_omit_timestamp"`
-	PingTimeout      config.Duration   `toml:"ping_timeout"`
-	ReadIdleTimeout  config.Duration   `toml:"read_idle_timeout"`
+	URLs                    []string          `toml:"urls"`
+	LocalAddr               string            `toml:"local_address"`
+	Token                   config.Secret     `toml:"token"`
+	Organization            string            `toml:"organization"`
+	Bucket                  string            `toml:"bucket"`
+	BucketTag               string            `toml:"bucket_tag"`
+	ExcludeBucketTag        bool              `toml:"exclude_bucket_tag"`
+	Timeout                 config.Duration   `toml:"timeout"`
+	HTTPHeaders             map[string]string `toml:"http_headers"`
+	HTTPProxy               string            `toml:"http_proxy"`
+	UserAgent               string            `toml:"user_agent"`
+	ContentEncoding         string            `toml:"content_encoding"`
+	ContentEncodingLevel    int               `toml:"content_encoding_level"`
+	UintSupport             bool              `toml:"influx_uint_support"`
+	OmitTimestamp           bool              `toml:"influx_omit_timestamp"`
+	Precision               string            `toml:"precision"`
+	FieldTypes              map[string]string `toml:"field_types"`
+	NoFieldsBehavior        string            `toml:"no_fields_behavior"`
+	Deduplicate             bool              `toml:"deduplicate"`
+	MeasurementPrefix       string            `toml:"measurement_prefix"`
+	CorrelationHeader       string            `toml:"correlation_header"`
+	CorrelationTag          string            `toml:"correlation_tag"`
+	DeferTokenResolution    bool              `toml:"defer_token_resolution"`
+	PingTimeout             config.Duration   `toml:"ping_timeout"`
+	ReadIdleTimeout         config.Duration   `toml:"read_idle_timeout"`
+	LatencyWarningThreshold config.Duration   `toml:"latency_warning_threshold"`
+	SplitOnTooLarge         bool              `toml:"split_on_too_large"`
+	PingOnConnect           bool              `toml:"ping_on_connect"`
+	LoadBalanceStrategy     string            `toml:"load_balance_strategy"`
+	APIVersion              string            `toml:"api_version"`
+	DeadLetterFile          string            `toml:"dead_letter_file"`
+	DeadLetterMaxSize       config.Size       `toml:"dead_letter_max_size"`
+	DeadLetterMaxArchives   int               `toml:"dead_letter_max_archives"`
+
+	TLSPerURL map[string]*tls.ClientConfig `toml:"tls_per_url"`
 	tls.ClientConfig
 
 	Log telegraf.Logger `toml:"-"`
 
-	clients []Client
+	clients    []Client
+	next       atomic.Uint64
+	deadLetter io.WriteCloser
 }
 
 func (*InfluxDB) SampleConfig() string {
@@ -69,6 +104,66 @@ func (i *InfluxDB) Connect() error {
 		i.URLs = append(i.URLs, defaultURL)
 	}
 
+	switch i.NoFieldsBehavior {
+	case "":
+		i.NoFieldsBehavior = "drop"
+	case "drop", "warn":
+	default:
+		return fmt.Errorf("invalid no_fields_behavior %q", i.NoFieldsBehavior)
+	}
+
+	if i.ContentEncodingLevel < 0 || i.ContentEncodingLevel > 9 {
+		return fmt.Errorf("invalid content_encoding_level %d", i.ContentEncodingLevel)
+	}
+
+	switch i.LoadBalanceStrategy {
+	case "":
+		i.LoadBalanceStrategy = "random"
+	case "random", "round-robin", "failover":
+	default:
+		return fmt.Errorf("invalid load_balance_strategy %q", i.LoadBalanceStrategy)
+	}
+
+	switch i.APIVersion {
+	case "":
+		i.APIVersion = "v2"
+	case "v2", "v3":
+	default:
+		return fmt.Errorf("invalid api_version %q", i.APIVersion)
+	}
+
+	if i.Precision == "" {
+		i.Precision = "ns"
+	}
+	if _, found := precisions[i.Precision]; !found {
+		return fmt.Errorf("invalid precision %q", i.Precision)
+	}
+
+	for field, fieldType := range i.FieldTypes {
+		switch fieldType {
+		case "int", "uint":
+		default:
+			return fmt.Errorf("invalid type %q for field %q", fieldType, field)
+		}
+	}
+
+	for u, cfg := range i.TLSPerURL {
+		if !slices.Contains(i.URLs, u) {
+			return fmt.Errorf("tls_per_url contains settings for unknown url %q", u)
+		}
+		if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+			return fmt.Errorf("tls_per_url for %q requires both tls_cert and tls_key to be set", u)
+		}
+	}
+
+	if i.DeadLetterFile != "" && i.deadLetter == nil {
+		w, err := rotate.NewFileWriter(i.DeadLetterFile, 0, int64(i.DeadLetterMaxSize), i.DeadLetterMaxArchives)
+		if err != nil {
+			return fmt.Errorf("opening dead-letter file failed: %w", err)
+		}
+		i.deadLetter = w
+	}
+
 	for _, u := range i.URLs {
 		parts, err := url.Parse(u)
 		if err != nil {
@@ -112,11 +207,23 @@ func (i *InfluxDB) Connect() error {
 
 		switch parts.Scheme {
 		case "http", "https", "unix":
-			c, err := i.getHTTPClient(parts, localAddr, proxy)
+			tlsCfg := &i.ClientConfig
+			if cfg, found := i.TLSPerURL[u]; found {
+				tlsCfg = cfg
+			}
+
+			c, err := i.getHTTPClient(parts, localAddr, proxy, tlsCfg)
 			if err != nil {
 				return err
 			}
 
+			if i.PingOnConnect {
+				if err := c.Ping(context.Background()); err != nil {
+					c.Close()
+					return fmt.Errorf("health check for %q failed: %w", parts.Redacted(), err)
+				}
+			}
+
 			i.clients = append(i.clients, c)
 		default:
 			return fmt.Errorf("unsupported scheme [%q]: %q", u, parts.Scheme)
@@ -130,6 +237,12 @@ func (i *InfluxDB) Close() error {
 	for _, client := range i.clients {
 		client.Close()
 	}
+	if i.deadLetter != nil {
+		if err := i.deadLetter.Close(); err != nil {
+			return fmt.Errorf("closing dead-letter file failed: %w", err)
+		}
+		i.deadLetter = nil
+	}
 	return nil
 }
 
@@ -138,11 +251,38 @@ func (i *InfluxDB) Close() error {
 func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 	ctx := context.Background()
 
-	var err error
-	p := rand.Perm(len(i.clients))
-	for _, n := range p {
+	metrics = i.dropMetricsWithoutFields(metrics)
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if i.Deduplicate {
+		metrics = deduplicate(metrics)
+	}
+
+	if len(i.FieldTypes) > 0 {
+		metrics = i.coerceFieldTypes(metrics)
+	}
+
+	if i.MeasurementPrefix != "" {
+		metrics = i.prefixMeasurements(metrics)
+	}
+
+	// Skip servers backing off to not flood the log with errors and remember
+	// the shortest time to wait in case all servers are backing off.
+	var wait time.Duration
+	var attempted bool
+	for _, n := range i.serverOrder() {
 		client := i.clients[n]
-		err = client.Write(ctx, metrics)
+		if d := client.RetryAfter(); d > 0 {
+			if wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		attempted = true
+		err := client.Write(ctx, metrics)
 		if err == nil {
 			return nil
 		}
@@ -150,11 +290,148 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 		i.Log.Errorf("When writing to [%s]: %v", client.URL(), err)
 	}
 
+	if !attempted && wait > 0 {
+		return &RetryError{RetryAfter: wait}
+	}
+
 	return errors.New("failed to send metrics to any configured server(s)")
 }
 
-func (i *InfluxDB) getHTTPClient(address *url.URL, localAddr *net.TCPAddr, proxy *url.URL) (Client, error) {
-	tlsConfig, err := i.ClientConfig.TLSConfig()
+// serverOrder returns the order of the servers to try according to the load
+// balancing strategy.
+func (i *InfluxDB) serverOrder() []int {
+	switch i.LoadBalanceStrategy {
+	case "round-robin":
+		start := int((i.next.Add(1) - 1) % uint64(len(i.clients)))
+		order := make([]int, 0, len(i.clients))
+		for n := range len(i.clients) {
+			order = append(order, (start+n)%len(i.clients))
+		}
+		return order
+	case "failover":
+		order := make([]int, 0, len(i.clients))
+		for n := range len(i.clients) {
+			order = append(order, n)
+		}
+		return order
+	}
+	return rand.Perm(len(i.clients))
+}
+
+// dropMetricsWithoutFields removes all metrics without fields as those cannot
+// be serialized and would otherwise pollute the batch.
+func (i *InfluxDB) dropMetricsWithoutFields(metrics []telegraf.Metric) []telegraf.Metric {
+	filtered := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if len(m.FieldList()) == 0 {
+			if i.NoFieldsBehavior == "warn" {
+				i.Log.Warnf("Dropping metric %q without fields", m.Name())
+			}
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+
+	return filtered
+}
+
+// deduplicate merges the fields of metrics of the same series and timestamp
+// into the first occurrence with the last value winning. Metrics are copied
+// before modification to not alter the original metric in case we need to
+// retry the request.
+func deduplicate(metrics []telegraf.Metric) []telegraf.Metric {
+	type seriesKey struct {
+		id uint64
+		ts int64
+	}
+
+	positions := make(map[seriesKey]int, len(metrics))
+	copied := make(map[int]bool)
+	deduped := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		key := seriesKey{id: m.HashID(), ts: m.Time().UnixNano()}
+		if idx, found := positions[key]; found {
+			if !copied[idx] {
+				deduped[idx] = deduped[idx].Copy()
+				copied[idx] = true
+			}
+			for _, field := range m.FieldList() {
+				deduped[idx].AddField(field.Key, field.Value)
+			}
+			continue
+		}
+		positions[key] = len(deduped)
+		deduped = append(deduped, m)
+	}
+
+	return deduped
+}
+
+// coerceFieldTypes converts the fields configured in FieldTypes to the given
+// type. Metrics are copied before modification to not alter the original
+// metric in case we need to retry the request.
+func (i *InfluxDB) coerceFieldTypes(metrics []telegraf.Metric) []telegraf.Metric {
+	coerced := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		var modified telegraf.Metric
+		for _, field := range m.FieldList() {
+			fieldType, found := i.FieldTypes[field.Key]
+			if !found {
+				continue
+			}
+
+			var v interface{}
+			var err error
+			switch fieldType {
+			case "int":
+				if _, ok := field.Value.(int64); ok {
+					continue
+				}
+				v, err = internal.ToInt64(field.Value)
+			case "uint":
+				if _, ok := field.Value.(uint64); ok {
+					continue
+				}
+				v, err = internal.ToUint64(field.Value)
+			}
+			if err != nil {
+				i.Log.Debugf("Cannot convert field %q of metric %q to %s: %v", field.Key, m.Name(), fieldType, err)
+				continue
+			}
+
+			if modified == nil {
+				modified = m.Copy()
+				modified.Accept()
+			}
+			modified.AddField(field.Key, v)
+		}
+
+		if modified != nil {
+			coerced = append(coerced, modified)
+		} else {
+			coerced = append(coerced, m)
+		}
+	}
+
+	return coerced
+}
+
+// prefixMeasurements prepends the configured prefix to the measurement names.
+// Metrics are copied before renaming to not affect other outputs.
+func (i *InfluxDB) prefixMeasurements(metrics []telegraf.Metric) []telegraf.Metric {
+	prefixed := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		renamed := m.Copy()
+		renamed.Accept()
+		renamed.SetName(i.MeasurementPrefix + m.Name())
+		prefixed = append(prefixed, renamed)
+	}
+
+	return prefixed
+}
+
+func (i *InfluxDB) getHTTPClient(address *url.URL, localAddr *net.TCPAddr, proxy *url.URL, tlsCfg *tls.ClientConfig) (Client, error) {
+	tlsConfig, err := tlsCfg.TLSConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -162,29 +439,40 @@ func (i *InfluxDB) getHTTPClient(address *url.URL, localAddr *net.TCPAddr, proxy
 	serializer := &influx.Serializer{
 		UintSupport:   i.UintSupport,
 		OmitTimestamp: i.OmitTimestamp,
+
+		TimestampPrecision: precisions[i.Precision],
 	}
 	if err := serializer.Init(); err != nil {
 		return nil, err
 	}
 
 	httpConfig := &HTTPConfig{
-		URL:              address,
-		LocalAddr:        localAddr,
-		Token:            i.Token,
-		Organization:     i.Organization,
-		Bucket:           i.Bucket,
-		BucketTag:        i.BucketTag,
-		ExcludeBucketTag: i.ExcludeBucketTag,
-		Timeout:          time.Duration(i.Timeout),
-		Headers:          i.HTTPHeaders,
-		Proxy:            proxy,
-		UserAgent:        i.UserAgent,
-		ContentEncoding:  i.ContentEncoding,
-		TLSConfig:        tlsConfig,
-		Serializer:       serializer,
-		PingTimeout:      i.PingTimeout,
-		ReadIdleTimeout:  i.ReadIdleTimeout,
-		Log:              i.Log,
+		URL:                     address,
+		LocalAddr:               localAddr,
+		Token:                   i.Token,
+		Organization:            i.Organization,
+		Bucket:                  i.Bucket,
+		BucketTag:               i.BucketTag,
+		ExcludeBucketTag:        i.ExcludeBucketTag,
+		CorrelationHeader:       i.CorrelationHeader,
+		CorrelationTag:          i.CorrelationTag,
+		Precision:               i.Precision,
+		APIVersion:              i.APIVersion,
+		DeadLetter:              i.deadLetter,
+		DeferTokenResolution:    i.DeferTokenResolution,
+		DisableSplitOnTooLarge:  !i.SplitOnTooLarge,
+		Timeout:                 time.Duration(i.Timeout),
+		Headers:                 i.HTTPHeaders,
+		Proxy:                   proxy,
+		UserAgent:               i.UserAgent,
+		ContentEncoding:         i.ContentEncoding,
+		ContentEncodingLevel:    i.ContentEncodingLevel,
+		TLSConfig:               tlsConfig,
+		Serializer:              serializer,
+		PingTimeout:             i.PingTimeout,
+		ReadIdleTimeout:         i.ReadIdleTimeout,
+		LatencyWarningThreshold: time.Duration(i.LatencyWarningThreshold),
+		Log:                     i.Log,
 	}
 
 	c, err := NewHTTPClient(httpConfig)
@@ -198,8 +486,17 @@ func (i *InfluxDB) getHTTPClient(address *url.URL, localAddr *net.TCPAddr, proxy
 func init() {
 	outputs.Add("influxdb_v2", func() telegraf.Output {
 		return &InfluxDB{
-			Timeout:         config.Duration(time.Second * 5),
-			ContentEncoding: "gzip",
+			Timeout:          config.Duration(time.Second * 5),
+			ContentEncoding:  "gzip",
+			NoFieldsBehavior: "drop",
+			Precision:        "ns",
+			SplitOnTooLarge:  true,
+
+			LoadBalanceStrategy: "random",
+			APIVersion:          "v2",
+
+			DeadLetterMaxSize:     config.Size(10 * 1024 * 1024),
+			DeadLetterMaxArchives: 1,
 		}
 	})
 }
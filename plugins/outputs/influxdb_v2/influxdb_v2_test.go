@@ -1,12 +1,29 @@
 package influxdb_v2_test
 
 import (
+	"compress/gzip"
+	"errors"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	influxdb "github.com/influxdata/telegraf/plugins/outputs/influxdb_v2"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -112,3 +129,897 @@ func TestInfluxDBLocalAddress(t *testing.T) {
 	require.NoError(t, output.Connect())
 	require.NoError(t, output.Close())
 }
+
+func TestFieldTypesInvalid(t *testing.T) {
+	output := influxdb.InfluxDB{
+		URLs:       []string{"http://localhost:8086"},
+		FieldTypes: map[string]string{"value": "float"},
+	}
+	require.ErrorContains(t, output.Connect(), `invalid type "float" for field "value"`)
+}
+
+func TestFieldTypes(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body = string(buf)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:            []string{ts.URL},
+		ContentEncoding: "identity",
+		UintSupport:     true,
+		FieldTypes: map[string]string{
+			"uptime":  "uint",
+			"counter": "int",
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	input := testutil.MustMetric(
+		"test",
+		map[string]string{},
+		map[string]interface{}{
+			"uptime":  int64(42),
+			"counter": uint64(23),
+			"value":   int64(1),
+		},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, output.Write([]telegraf.Metric{input}))
+	require.Contains(t, body, "uptime=42u")
+	require.Contains(t, body, "counter=23i")
+	require.Contains(t, body, "value=1i")
+
+	// The original metric must not be modified
+	v, found := input.GetField("uptime")
+	require.True(t, found)
+	require.Equal(t, int64(42), v)
+}
+
+func TestNoFieldsBehavior(t *testing.T) {
+	var requests int
+	var body string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body = string(buf)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:             []string{ts.URL},
+		ContentEncoding:  "identity",
+		NoFieldsBehavior: "warn",
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	empty := testutil.MustMetric("empty", map[string]string{"host": "a"}, map[string]interface{}{}, time.Unix(0, 0))
+	valid := testutil.MustMetric("valid", map[string]string{"host": "a"}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+
+	// Only metrics without fields should not issue a request
+	require.NoError(t, output.Write([]telegraf.Metric{empty}))
+	require.Zero(t, requests)
+
+	require.NoError(t, output.Write([]telegraf.Metric{empty, valid}))
+	require.Equal(t, 1, requests)
+	require.Equal(t, "valid,host=a value=42i 0\n", body)
+}
+
+func TestNoFieldsBehaviorInvalid(t *testing.T) {
+	output := influxdb.InfluxDB{
+		URLs:             []string{"http://localhost:8086"},
+		NoFieldsBehavior: "fail",
+	}
+	require.ErrorContains(t, output.Connect(), `invalid no_fields_behavior "fail"`)
+}
+
+func TestPrecision(t *testing.T) {
+	var body, precision string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			precision = r.URL.Query().Get("precision")
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body = string(buf)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:            []string{ts.URL},
+		ContentEncoding: "identity",
+		Precision:       "ms",
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(1, 234567890))
+	require.NoError(t, output.Write([]telegraf.Metric{m}))
+	require.Equal(t, "ms", precision)
+	require.Equal(t, "cpu value=42i 1234\n", body)
+}
+
+func TestPrecisionInvalid(t *testing.T) {
+	output := influxdb.InfluxDB{
+		URLs:      []string{"http://localhost:8086"},
+		Precision: "m",
+	}
+	require.ErrorContains(t, output.Connect(), `invalid precision "m"`)
+}
+
+func TestDeduplicate(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body = string(buf)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:            []string{ts.URL},
+		ContentEncoding: "identity",
+		Deduplicate:     true,
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 4}, time.Unix(1, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	expected := "cpu,host=a value=3i 0\n" +
+		"cpu,host=b value=2i 0\n" +
+		"cpu,host=a value=4i 1000000000\n"
+	require.Equal(t, expected, body)
+}
+
+func TestDeduplicateMergeFields(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body = string(buf)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:            []string{ts.URL},
+		ContentEncoding: "identity",
+		Deduplicate:     true,
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"x": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"y": 2}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+	require.Equal(t, "cpu,host=a x=1i,y=2i 0\n", body)
+
+	// The original metrics must not be modified to allow retrying the write
+	require.Len(t, metrics[0].FieldList(), 1)
+}
+
+func TestMeasurementPrefix(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body = string(buf)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:              []string{ts.URL},
+		ContentEncoding:   "identity",
+		MeasurementPrefix: "staging_",
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+	require.Equal(t, "staging_cpu,host=a value=1i 0\n", body)
+
+	// The original metric must not be modified
+	require.Equal(t, "cpu", metrics[0].Name())
+}
+
+func TestCorrelationHeader(t *testing.T) {
+	var ids []string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, r.Header.Get("X-Request-ID"))
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:              []string{ts.URL},
+		CorrelationHeader: "X-Request-ID",
+		CorrelationTag:    "trace_id",
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	// Use the tag value if present
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"trace_id": "abc"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	// Generate an ID otherwise
+	metrics = []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	require.Len(t, ids, 2)
+	require.Equal(t, "abc", ids[0])
+	require.Len(t, ids[1], 36)
+}
+
+func TestDeferTokenResolution(t *testing.T) {
+	var auth string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	// Simulate a secret-store becoming unavailable after linking
+	available := true
+	token := config.NewSecret([]byte("@{mock:token}"))
+	require.NoError(t, token.Link(map[string]telegraf.ResolveFunc{
+		"@{mock:token}": func() ([]byte, bool, error) {
+			if !available {
+				return nil, true, errors.New("store unavailable")
+			}
+			return []byte("secret"), true, nil
+		},
+	}))
+	available = false
+
+	output := influxdb.InfluxDB{
+		URLs:  []string{ts.URL},
+		Token: token,
+		Log:   testutil.Logger{},
+	}
+	require.ErrorContains(t, output.Connect(), "getting token failed")
+
+	output.DeferTokenResolution = true
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.Error(t, output.Write(metrics))
+
+	// Wait for the retry time to elapse
+	available = true
+	require.Eventually(t, func() bool {
+		return output.Write(metrics) == nil
+	}, 3*time.Second, 50*time.Millisecond)
+	require.Equal(t, "Token secret", auth)
+}
+
+func TestTokenRotation(t *testing.T) {
+	var auth []string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = append(auth, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	// Simulate a dynamic secret rotated between writes
+	version := 1
+	token := config.NewSecret([]byte("@{mock:token}"))
+	require.NoError(t, token.Link(map[string]telegraf.ResolveFunc{
+		"@{mock:token}": func() ([]byte, bool, error) {
+			return []byte("secret" + strconv.Itoa(version)), true, nil
+		},
+	}))
+
+	output := influxdb.InfluxDB{
+		URLs:  []string{ts.URL},
+		Token: token,
+		Log:   testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+	version = 2
+	require.NoError(t, output.Write(metrics))
+
+	require.Equal(t, []string{"Token secret1", "Token secret2"}, auth)
+}
+
+func TestPingOnConnect(t *testing.T) {
+	var status int
+	var body string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/health" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}),
+	)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{
+			name:   "healthy",
+			status: http.StatusOK,
+			body:   `{"name":"influxdb","status":"pass","message":"ready for queries and writes"}`,
+		},
+		{
+			name:     "unhealthy",
+			status:   http.StatusServiceUnavailable,
+			body:     `{"name":"influxdb","status":"fail","message":"not ready"}`,
+			expected: "server returned 503 Service Unavailable: not ready",
+		},
+		{
+			name:     "failing status",
+			status:   http.StatusOK,
+			body:     `{"name":"influxdb","status":"fail","message":"degraded"}`,
+			expected: `server reported status "fail": degraded`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			body = tt.body
+
+			output := influxdb.InfluxDB{
+				URLs:          []string{ts.URL},
+				PingOnConnect: true,
+				Log:           testutil.Logger{},
+			}
+			err := output.Connect()
+			if tt.expected == "" {
+				require.NoError(t, err)
+				output.Close()
+				return
+			}
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestPingOnConnectUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:          []string{ts.URL},
+		Timeout:       config.Duration(time.Second),
+		PingOnConnect: true,
+		Log:           testutil.Logger{},
+	}
+	require.ErrorContains(t, output.Connect(), "health check")
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests atomic.Int64
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs: []string{ts.URL},
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, output.Write(metrics), "failed to send metrics")
+
+	// The server must not be contacted while backing off
+	var retryErr *influxdb.RetryError
+	require.ErrorAs(t, output.Write(metrics), &retryErr)
+	require.Greater(t, retryErr.RetryAfter, 50*time.Second)
+	require.LessOrEqual(t, retryErr.RetryAfter, 60*time.Second)
+	require.EqualValues(t, 1, requests.Load())
+}
+
+func TestLatencyWarning(t *testing.T) {
+	var delay atomic.Int64
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(time.Duration(delay.Load()))
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	logger := &testutil.CaptureLogger{}
+	output := influxdb.InfluxDB{
+		URLs:                    []string{ts.URL},
+		LatencyWarningThreshold: config.Duration(50 * time.Millisecond),
+		Log:                     logger,
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+
+	// Only warn once for subsequent slow writes
+	delay.Store(int64(100 * time.Millisecond))
+	require.NoError(t, output.Write(metrics))
+	require.NoError(t, output.Write(metrics))
+	require.Len(t, logger.Warnings(), 1)
+	require.Contains(t, logger.Warnings()[0], "exceeding the threshold")
+
+	// Warn again after the latency was back to normal
+	delay.Store(0)
+	require.NoError(t, output.Write(metrics))
+	delay.Store(int64(100 * time.Millisecond))
+	require.NoError(t, output.Write(metrics))
+	require.Len(t, logger.Warnings(), 2)
+
+	var slowWrites interface{}
+	for _, m := range selfstat.Metrics() {
+		if m.Name() == "internal_influxdb_v2" {
+			if u, _ := m.GetTag("url"); u == ts.URL {
+				slowWrites, _ = m.GetField("slow_writes")
+			}
+		}
+	}
+	require.EqualValues(t, 3, slowWrites)
+}
+
+func TestLatencyWarningTimeout(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	u.User = url.UserPassword("user", "secret")
+
+	logger := &testutil.CaptureLogger{}
+	output := influxdb.InfluxDB{
+		URLs:                    []string{u.String()},
+		Timeout:                 config.Duration(100 * time.Millisecond),
+		LatencyWarningThreshold: config.Duration(50 * time.Millisecond),
+		Log:                     logger,
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.Error(t, output.Write(metrics))
+	require.NotEmpty(t, logger.Warnings())
+	require.Contains(t, logger.Warnings()[0], "exceeding the threshold")
+
+	var slowWrites interface{}
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_influxdb_v2" {
+			continue
+		}
+		tag, _ := m.GetTag("url")
+		require.NotContains(t, tag, "secret")
+		if tag == u.Redacted() {
+			slowWrites, _ = m.GetField("slow_writes")
+		}
+	}
+	require.EqualValues(t, 1, slowWrites)
+}
+
+func TestContentEncodingLevel(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body, err = io.ReadAll(gz)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:                 []string{ts.URL},
+		ContentEncoding:      "gzip",
+		ContentEncodingLevel: 9,
+		Log:                  testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.NoError(t, output.Write([]telegraf.Metric{m}))
+	require.Equal(t, "cpu value=42i 0\n", string(body))
+}
+
+func TestContentEncodingLevelInvalid(t *testing.T) {
+	output := influxdb.InfluxDB{
+		URLs:                 []string{"http://localhost:8086"},
+		ContentEncodingLevel: 11,
+	}
+	require.ErrorContains(t, output.Connect(), "invalid content_encoding_level 11")
+}
+
+func TestLoadBalanceStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		expected []int64
+	}{
+		{
+			strategy: "round-robin",
+			expected: []int64{2, 2, 2},
+		},
+		{
+			strategy: "failover",
+			expected: []int64{6, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			counts := make([]atomic.Int64, 3)
+			urls := make([]string, 0, len(counts))
+			for idx := range counts {
+				ts := httptest.NewServer(
+					http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						counts[idx].Add(1)
+						w.WriteHeader(http.StatusNoContent)
+					}),
+				)
+				defer ts.Close()
+				urls = append(urls, ts.URL)
+			}
+
+			output := influxdb.InfluxDB{
+				URLs:                urls,
+				LoadBalanceStrategy: tt.strategy,
+				Log:                 testutil.Logger{},
+			}
+			require.NoError(t, output.Connect())
+			defer output.Close()
+
+			metrics := []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+			}
+			for range 6 {
+				require.NoError(t, output.Write(metrics))
+			}
+
+			actual := make([]int64, 0, len(counts))
+			for idx := range counts {
+				actual = append(actual, counts[idx].Load())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestLoadBalanceStrategyInvalid(t *testing.T) {
+	output := influxdb.InfluxDB{
+		URLs:                []string{"http://localhost:8086"},
+		LoadBalanceStrategy: "least-loaded",
+	}
+	require.ErrorContains(t, output.Connect(), `invalid load_balance_strategy "least-loaded"`)
+}
+
+func TestAPIVersionV3(t *testing.T) {
+	var path, db, org string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			db = r.URL.Query().Get("db")
+			org = r.URL.Query().Get("org")
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:         []string{ts.URL},
+		Organization: "influx",
+		Bucket:       "telegraf",
+		APIVersion:   "v3",
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.NoError(t, output.Write([]telegraf.Metric{m}))
+	require.Equal(t, "/api/v3/write_lp", path)
+	require.Equal(t, "telegraf", db)
+	require.Empty(t, org)
+}
+
+func TestDeadLetterFile(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"code":"unprocessable entity","message":"points beyond retention policy"}`))
+		}),
+	)
+	defer ts.Close()
+
+	filename := filepath.Join(t.TempDir(), "rejected.lp")
+	output := influxdb.InfluxDB{
+		URLs:            []string{ts.URL},
+		Bucket:          "telegraf",
+		ContentEncoding: "identity",
+		DeadLetterFile:  filename,
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+	require.NoError(t, output.Close())
+
+	buf, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	require.Len(t, lines, 3)
+	require.True(t, strings.HasPrefix(lines[0], "# "))
+	require.Contains(t, lines[0], `bucket="telegraf": unprocessable entity: points beyond retention policy`)
+	require.Equal(t, []string{"cpu value=1i 0", "mem value=2i 0"}, lines[1:])
+}
+
+func TestTLSPerURL(t *testing.T) {
+	pki := testutil.NewPKI("../../../testutil/pki")
+	serverTLS, err := pki.TLSServerConfig().TLSConfig()
+	require.NoError(t, err)
+
+	var requests atomic.Int64
+	ts := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	ts.TLS = serverTLS
+	ts.StartTLS()
+	defer ts.Close()
+
+	// The server requires a client certificate only configured for the URL
+	u := "https://localhost:" + strconv.Itoa(ts.Listener.Addr().(*net.TCPAddr).Port)
+	output := influxdb.InfluxDB{
+		URLs:      []string{u},
+		TLSPerURL: map[string]*tls.ClientConfig{u: pki.TLSClientConfig()},
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.NoError(t, output.Write([]telegraf.Metric{m}))
+	require.EqualValues(t, 1, requests.Load())
+}
+
+func TestTLSPerURLInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		tls      *tls.ClientConfig
+		url      string
+		expected string
+	}{
+		{
+			name:     "unknown url",
+			tls:      &tls.ClientConfig{},
+			url:      "http://localhost:9999",
+			expected: `tls_per_url contains settings for unknown url "http://localhost:9999"`,
+		},
+		{
+			name:     "missing key",
+			tls:      &tls.ClientConfig{TLSCert: "cert.pem"},
+			url:      "http://localhost:8086",
+			expected: `tls_per_url for "http://localhost:8086" requires both tls_cert and tls_key to be set`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := influxdb.InfluxDB{
+				URLs:      []string{"http://localhost:8086"},
+				TLSPerURL: map[string]*tls.ClientConfig{tt.url: tt.tls},
+			}
+			require.ErrorContains(t, output.Connect(), tt.expected)
+		})
+	}
+}
+
+func TestBytesWritten(t *testing.T) {
+	wire := make(map[string]int64)
+	var mu sync.Mutex
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			mu.Lock()
+			wire[r.URL.Query().Get("bucket")] += int64(len(buf))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	output := influxdb.InfluxDB{
+		URLs:            []string{ts.URL},
+		Bucket:          "telegraf",
+		BucketTag:       "bucket",
+		ContentEncoding: "gzip",
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"bucket": "foo"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"bucket": "bar"}, map[string]interface{}{"value": 22}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	actual := make(map[string][2]interface{})
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_influxdb_v2" {
+			continue
+		}
+		if u, _ := m.GetTag("url"); u != ts.URL {
+			continue
+		}
+		bucket, _ := m.GetTag("bucket")
+		compressed, _ := m.GetField("bytes_written")
+		uncompressed, _ := m.GetField("bytes_written_uncompressed")
+		actual[bucket] = [2]interface{}{compressed, uncompressed}
+	}
+
+	expected := map[string][2]interface{}{
+		"foo": {wire["foo"], int64(len("cpu,bucket=foo value=1i 0\n"))},
+		"bar": {wire["bar"], int64(len("cpu,bucket=bar value=22i 0\n"))},
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestBytesWrittenRedactedURL(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	u.User = url.UserPassword("user", "secret")
+
+	output := influxdb.InfluxDB{
+		URLs: []string{u.String()},
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	var found bool
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_influxdb_v2" {
+			continue
+		}
+		if _, ok := m.GetField("bytes_written"); !ok {
+			continue
+		}
+		tag, _ := m.GetTag("url")
+		require.NotContains(t, tag, "secret")
+		if tag == u.Redacted() {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
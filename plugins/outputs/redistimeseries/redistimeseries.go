@@ -3,38 +3,126 @@ package redistimeseries
 
 import (
 	"context"
+	"crypto/tls"
 	_ "embed"
 	"errors"
 	"fmt"
+	"maps"
 	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/plugins/common/tls"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
 //go:embed sample.conf
 var sampleConfig string
 
+const defaultKeyTemplate = "{{.Name}}_{{.FieldName}}"
+
 type RedisTimeSeries struct {
 	Address             string          `toml:"address"`
+	Addresses           []string        `toml:"addresses"`
+	Cluster             bool            `toml:"cluster"`
+	SentinelMaster      string          `toml:"sentinel_master"`
+	SentinelUsername    config.Secret   `toml:"sentinel_username"`
+	SentinelPassword    config.Secret   `toml:"sentinel_password"`
 	Username            config.Secret   `toml:"username"`
 	Password            config.Secret   `toml:"password"`
 	Database            int             `toml:"database"`
 	ConvertStringFields bool            `toml:"convert_string_fields"`
+	ConvertBoolFields   bool            `toml:"convert_bool_fields"`
+	KeyTemplate         string          `toml:"key_template"`
+	LabelInclude        []string        `toml:"label_include"`
+	LabelExclude        []string        `toml:"label_exclude"`
+	UpdateLabels        bool            `toml:"update_labels"`
+	Retention           config.Duration `toml:"retention"`
+	DuplicatePolicy     string          `toml:"duplicate_policy"`
 	Timeout             config.Duration `toml:"timeout"`
 	Log                 telegraf.Logger `toml:"-"`
-	tls.ClientConfig
-	client *redis.Client
+	tlsint.ClientConfig
+	client    redis.UniversalClient
+	tlsConfig *tls.Config
+	tmpl      *template.Template
+
+	labelFilter filter.Filter
+	labels      map[string]map[string]string
+}
+
+// keyData is the data accessible in the key template
+type keyData struct {
+	telegraf.Metric
+	FieldName string
+}
+
+// Tag returns the value of the given tag or an empty string if the tag does
+// not exist
+func (d *keyData) Tag(key string) string {
+	v, _ := d.GetTag(key)
+	return v
+}
+
+func (r *RedisTimeSeries) Init() error {
+	if r.KeyTemplate == "" {
+		r.KeyTemplate = defaultKeyTemplate
+	}
+	tmpl, err := template.New("key").Parse(r.KeyTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing key template failed: %w", err)
+	}
+	r.tmpl = tmpl
+
+	labelFilter, err := filter.NewIncludeExcludeFilter(r.LabelInclude, r.LabelExclude)
+	if err != nil {
+		return fmt.Errorf("creating label filter failed: %w", err)
+	}
+	r.labelFilter = labelFilter
+
+	if r.Address != "" && len(r.Addresses) > 0 {
+		return errors.New("only one of 'address' and 'addresses' can be specified")
+	}
+	if r.Cluster && r.SentinelMaster != "" {
+		return errors.New("cluster and sentinel mode are mutually exclusive")
+	}
+	if r.Cluster && r.Database != 0 {
+		return errors.New("database selection is not supported in cluster mode")
+	}
+
+	if (r.TLSCert == "") != (r.TLSKey == "") {
+		return errors.New("'tls_cert' and 'tls_key' must be specified together")
+	}
+	tlsConfig, err := r.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("creating TLS config failed: %w", err)
+	}
+	r.tlsConfig = tlsConfig
+
+	if r.Retention < 0 {
+		return errors.New("retention must not be negative")
+	}
+	switch r.DuplicatePolicy {
+	case "", "block", "first", "last", "min", "max", "sum":
+	default:
+		return fmt.Errorf("invalid duplicate policy %q", r.DuplicatePolicy)
+	}
+
+	return nil
 }
 
 func (r *RedisTimeSeries) Connect() error {
-	if r.Address == "" {
+	addresses := r.Addresses
+	if r.Address != "" {
+		addresses = []string{r.Address}
+	}
+	if len(addresses) == 0 {
 		return errors.New("redis address must be specified")
 	}
 
@@ -50,12 +138,39 @@ func (r *RedisTimeSeries) Connect() error {
 	}
 	defer password.Destroy()
 
-	r.client = redis.NewClient(&redis.Options{
-		Addr:     r.Address,
-		Username: username.String(),
-		Password: password.String(),
-		DB:       r.Database,
-	})
+	sentinelUsername, err := r.SentinelUsername.Get()
+	if err != nil {
+		return fmt.Errorf("getting sentinel username failed: %w", err)
+	}
+	defer sentinelUsername.Destroy()
+
+	sentinelPassword, err := r.SentinelPassword.Get()
+	if err != nil {
+		return fmt.Errorf("getting sentinel password failed: %w", err)
+	}
+	defer sentinelPassword.Destroy()
+
+	options := &redis.UniversalOptions{
+		Addrs:            addresses,
+		Username:         username.String(),
+		Password:         password.String(),
+		SentinelUsername: sentinelUsername.String(),
+		SentinelPassword: sentinelPassword.String(),
+		MasterName:       r.SentinelMaster,
+		DB:               r.Database,
+		TLSConfig:        r.tlsConfig,
+	}
+
+	// Without cluster mode being forced, the client type is determined by the
+	// options, i.e. a sentinel client if a master name is specified, a cluster
+	// client for multiple addresses or a single-node client otherwise.
+	if r.Cluster {
+		r.client = redis.NewClusterClient(options.Cluster())
+	} else {
+		r.client = redis.NewUniversalClient(options)
+	}
+	r.labels = make(map[string]map[string]string)
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
 	defer cancel()
 	return r.client.Ping(ctx).Err()
@@ -76,9 +191,60 @@ func (r *RedisTimeSeries) Write(metrics []telegraf.Metric) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
 	defer cancel()
 
+	// Send all samples in one round-trip
+	pipe := r.client.Pipeline()
+	samples := r.queue(ctx, pipe, metrics)
+	if len(samples) == 0 {
+		return nil
+	}
+	_, execErr := pipe.Exec(ctx)
+
+	var errs []error
+	for _, s := range samples {
+		var failed bool
+		for _, cmd := range s.cmds {
+			if err := cmd.Err(); err != nil {
+				errs = append(errs, fmt.Errorf("adding sample %q of metric %q failed: %w", s.key, s.metric, err))
+				failed = true
+				break
+			}
+		}
+		if !failed {
+			r.labels[s.key] = s.labels
+		}
+	}
+	if len(errs) == len(samples) {
+		return fmt.Errorf("writing samples failed: %w", execErr)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d samples failed: %w", len(errs), len(samples), errors.Join(errs...))
+	}
+	return nil
+}
+
+// sample holds the commands queued for adding a field of a metric
+type sample struct {
+	key    string
+	metric string
+	labels map[string]string
+	cmds   []redis.Cmder
+}
+
+// queue adds the commands for writing the given metrics to the pipeline
+func (r *RedisTimeSeries) queue(ctx context.Context, pipe redis.Pipeliner, metrics []telegraf.Metric) []sample {
+	// Labels of the series queued in this batch to avoid resending them
+	queued := make(map[string]map[string]string)
+
+	samples := make([]sample, 0, len(metrics))
 	for _, m := range metrics {
+		labels := r.selectLabels(m)
 		for name, fv := range m.Fields() {
-			key := m.Name() + "_" + name
+			var buf strings.Builder
+			if err := r.tmpl.Execute(&buf, &keyData{Metric: m, FieldName: name}); err != nil {
+				r.Log.Errorf("Generating key for field %q of metric %q failed: %v", name, m.Name(), err)
+				continue
+			}
+			key := buf.String()
 
 			var value float64
 			switch v := fv.(type) {
@@ -95,28 +261,77 @@ func (r *RedisTimeSeries) Write(metrics []telegraf.Metric) error {
 					r.Log.Debugf("Converting string field %q of metric %q failed: %v", name, m.Name(), err)
 					continue
 				}
+			case bool:
+				if !r.ConvertBoolFields {
+					r.Log.Debugf("Dropping boolean field %q of metric %q", name, m.Name())
+					continue
+				}
+				if v {
+					value = 1
+				}
 			default:
 				var err error
 				value, err = internal.ToFloat64(v)
 				if err != nil {
-					r.Log.Errorf("Converting field %q (%T) of metric %q failed: %v", name, v, m.Name(), err)
+					r.Log.Debugf("Dropping field %q (%T) of metric %q: %v", name, v, m.Name(), err)
 					continue
 				}
 			}
 
-			resp := r.client.TSAddWithArgs(ctx, key, m.Time().UnixMilli(), value, &redis.TSOptions{Labels: m.Tags()})
-			if err := resp.Err(); err != nil {
-				return fmt.Errorf("adding sample %q failed: %w", key, err)
+			// Only send the labels if they changed since the last write to the
+			// series to avoid the overhead
+			options := &redis.TSOptions{}
+			current, found := queued[key]
+			if !found {
+				current, found = r.labels[key]
 			}
+			changed := !found || !maps.Equal(current, labels)
+			if changed {
+				options.Labels = labels
+			}
+
+			// The settings are only applied when creating the series
+			if !found {
+				options.Retention = int(time.Duration(r.Retention).Milliseconds())
+				options.DuplicatePolicy = strings.ToUpper(r.DuplicatePolicy)
+			}
+
+			s := sample{key: key, metric: m.Name(), labels: labels}
+			s.cmds = append(s.cmds, pipe.TSAddWithArgs(ctx, key, m.Time().UnixMilli(), value, options))
+
+			// TS.ADD only sets the labels when creating the series so we need
+			// to explicitly update them for existing series
+			if changed && r.UpdateLabels {
+				s.cmds = append(s.cmds, pipe.TSAlter(ctx, key, &redis.TSAlterOptions{Labels: labels}))
+			}
+			queued[key] = labels
+			samples = append(samples, s)
 		}
 	}
-	return nil
+	return samples
+}
+
+// selectLabels returns the tags of the metric to use as labels
+func (r *RedisTimeSeries) selectLabels(m telegraf.Metric) map[string]string {
+	var labels map[string]string
+	for _, tag := range m.TagList() {
+		if !r.labelFilter.Match(tag.Key) {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[tag.Key] = tag.Value
+	}
+	return labels
 }
 
 func init() {
 	outputs.Add("redistimeseries", func() telegraf.Output {
 		return &RedisTimeSeries{
 			ConvertStringFields: true,
+			ConvertBoolFields:   true,
+			KeyTemplate:         defaultKeyTemplate,
 			Timeout:             config.Duration(10 * time.Second),
 		}
 	})
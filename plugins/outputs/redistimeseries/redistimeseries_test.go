@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +17,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/testutil"
@@ -36,14 +39,244 @@ func TestConnectAndWriteIntegration(t *testing.T) {
 	redis := &RedisTimeSeries{
 		Address:             fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort]),
 		ConvertStringFields: true,
+		ConvertBoolFields:   true,
 		Timeout:             config.Duration(10 * time.Second),
 	}
+	require.NoError(t, redis.Init())
+
 	// Verify that we can connect to the RedisTimeSeries server
 	require.NoError(t, redis.Connect())
 	// Verify that we can successfully write data to the RedisTimeSeries server
 	require.NoError(t, redis.Write(testutil.MockMetrics()))
 }
 
+func TestInit(t *testing.T) {
+	plugin := &RedisTimeSeries{}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, defaultKeyTemplate, plugin.KeyTemplate)
+
+	plugin = &RedisTimeSeries{KeyTemplate: "{{.Name"}
+	require.ErrorContains(t, plugin.Init(), "parsing key template failed")
+
+	plugin = &RedisTimeSeries{
+		Address:   "127.0.0.1:6379",
+		Addresses: []string{"127.0.0.1:7000", "127.0.0.1:7001"},
+	}
+	require.ErrorContains(t, plugin.Init(), "only one of 'address' and 'addresses'")
+
+	plugin = &RedisTimeSeries{Cluster: true, SentinelMaster: "mymaster"}
+	require.ErrorContains(t, plugin.Init(), "mutually exclusive")
+
+	plugin = &RedisTimeSeries{Cluster: true, Database: 1}
+	require.ErrorContains(t, plugin.Init(), "not supported in cluster mode")
+
+	plugin = &RedisTimeSeries{ClientConfig: tls.ClientConfig{TLSCert: "testdata/cert.pem"}}
+	require.ErrorContains(t, plugin.Init(), "'tls_cert' and 'tls_key' must be specified together")
+
+	plugin = &RedisTimeSeries{ClientConfig: tls.ClientConfig{TLSCA: "testdata/non_existing.pem"}}
+	require.ErrorContains(t, plugin.Init(), `could not read certificate "testdata/non_existing.pem"`)
+
+	plugin = &RedisTimeSeries{Retention: config.Duration(-time.Second)}
+	require.ErrorContains(t, plugin.Init(), "retention must not be negative")
+
+	plugin = &RedisTimeSeries{DuplicatePolicy: "foo"}
+	require.ErrorContains(t, plugin.Init(), `invalid duplicate policy "foo"`)
+}
+
+func TestKeyTemplate(t *testing.T) {
+	m := metric.New(
+		"weather",
+		map[string]string{"location": "somewhere"},
+		map[string]interface{}{"temperature": 23.1},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: "weather_temperature",
+		},
+		{
+			name:     "with tag",
+			template: `telegraf:{{.Tag "location"}}:{{.Name}}:{{.FieldName}}`,
+			expected: "telegraf:somewhere:weather:temperature",
+		},
+		{
+			name:     "missing tag",
+			template: `{{.Tag "host"}}:{{.Name}}`,
+			expected: ":weather",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &RedisTimeSeries{KeyTemplate: tt.template}
+			require.NoError(t, plugin.Init())
+
+			var buf strings.Builder
+			require.NoError(t, plugin.tmpl.Execute(&buf, &keyData{Metric: m, FieldName: "temperature"}))
+			require.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestSelectLabels(t *testing.T) {
+	m := metric.New(
+		"weather",
+		map[string]string{"location": "somewhere", "host": "localhost", "source": "sensor"},
+		map[string]interface{}{"temperature": 23.1},
+		time.Unix(0, 0),
+	)
+
+	plugin := &RedisTimeSeries{}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, m.Tags(), plugin.selectLabels(m))
+
+	plugin = &RedisTimeSeries{
+		LabelInclude: []string{"location", "s*"},
+		LabelExclude: []string{"source"},
+	}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, map[string]string{"location": "somewhere"}, plugin.selectLabels(m))
+
+	plugin = &RedisTimeSeries{LabelExclude: []string{"*"}}
+	require.NoError(t, plugin.Init())
+	require.Nil(t, plugin.selectLabels(m))
+}
+
+func TestQueue(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New(
+			"weather",
+			map[string]string{"location": "somewhere"},
+			map[string]interface{}{"temperature": 23.1, "humidity": 52.3, "status": "ok"},
+			time.Unix(1696489223, 0),
+		),
+		metric.New(
+			"weather",
+			map[string]string{"location": "somewhere"},
+			map[string]interface{}{"temperature": 23.2, "humidity": 52.1, "status": "ok"},
+			time.Unix(1696489224, 0),
+		),
+		metric.New(
+			"weather",
+			map[string]string{"location": "somewhereelse"},
+			map[string]interface{}{"temperature": 23.3, "humidity": 52.0, "status": "ok"},
+			time.Unix(1696489225, 0),
+		),
+	}
+
+	tests := []struct {
+		name         string
+		updateLabels bool
+		expected     int
+	}{
+		{
+			name:     "add only",
+			expected: 6,
+		},
+		{
+			name:         "with label updates",
+			updateLabels: true,
+			expected:     10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &RedisTimeSeries{
+				UpdateLabels: tt.updateLabels,
+				Log:          testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			// Queue the commands without executing them
+			client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+			defer client.Close()
+			pipe := client.Pipeline()
+
+			samples := plugin.queue(context.Background(), pipe, metrics)
+			require.Len(t, samples, 6)
+			require.Equal(t, tt.expected, pipe.Len())
+
+			// Labels should only be sent for new series or if they changed
+			var withLabels int
+			for _, s := range samples {
+				if slices.Contains(s.cmds[0].Args(), "LABELS") {
+					withLabels++
+				}
+			}
+			require.Equal(t, 4, withLabels)
+		})
+	}
+}
+
+func TestQueueFieldTypes(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New(
+			"example",
+			map[string]string{},
+			map[string]interface{}{
+				"value":       int64(42),
+				"counter":     uint64(23),
+				"ratio":       0.5,
+				"hours":       "10",
+				"status":      "OK",
+				"operational": true,
+			},
+			time.Unix(1696489223, 0),
+		),
+	}
+
+	tests := []struct {
+		name          string
+		convertString bool
+		convertBool   bool
+		expected      []string
+	}{
+		{
+			name:     "numeric only",
+			expected: []string{"example_counter", "example_ratio", "example_value"},
+		},
+		{
+			name:        "convert bool",
+			convertBool: true,
+			expected:    []string{"example_counter", "example_operational", "example_ratio", "example_value"},
+		},
+		{
+			name:          "convert string and bool",
+			convertString: true,
+			convertBool:   true,
+			expected:      []string{"example_counter", "example_hours", "example_operational", "example_ratio", "example_value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &RedisTimeSeries{
+				ConvertStringFields: tt.convertString,
+				ConvertBoolFields:   tt.convertBool,
+				Log:                 testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+			defer client.Close()
+
+			samples := plugin.queue(context.Background(), client.Pipeline(), metrics)
+			keys := make([]string, 0, len(samples))
+			for _, s := range samples {
+				keys = append(keys, s.key)
+			}
+			require.ElementsMatch(t, tt.expected, keys)
+		})
+	}
+}
+
 func TestCases(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -58,6 +291,7 @@ func TestCases(t *testing.T) {
 	outputs.Add("redistimeseries", func() telegraf.Output {
 		return &RedisTimeSeries{
 			ConvertStringFields: true,
+			ConvertBoolFields:   true,
 			Timeout:             config.Duration(10 * time.Second),
 		}
 	})
@@ -120,6 +354,7 @@ func TestCases(t *testing.T) {
 			plugin := cfg.Outputs[0].Output.(*RedisTimeSeries)
 			plugin.Address = address
 			plugin.Log = testutil.Logger{}
+			require.NoError(t, plugin.Init())
 
 			// Connect and write the metric(s)
 			require.NoError(t, plugin.Connect())
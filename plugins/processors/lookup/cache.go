@@ -0,0 +1,68 @@
+package lookup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// sharedFiles holds the content of the lookup files shared read-only across
+// all processor instances
+var sharedFiles = &fileCache{entries: make(map[fileCacheKey]map[string][]telegraf.Tag)}
+
+type fileCacheKey struct {
+	filename string
+	format   string
+	modtime  time.Time
+	size     int64
+}
+
+type fileCache struct {
+	entries map[fileCacheKey]map[string][]telegraf.Tag
+	sync.Mutex
+}
+
+// load returns the cached mappings of the given file or loads the file using
+// the given function in case the file is not cached or changed.
+func (c *fileCache) load(fn, format string, load func(string) (map[string][]telegraf.Tag, error)) (map[string][]telegraf.Tag, error) {
+	filename, err := filepath.Abs(fn)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q failed: %w", fn, err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
+	}
+	key := fileCacheKey{
+		filename: filename,
+		format:   format,
+		modtime:  info.ModTime(),
+		size:     info.Size(),
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if mappings, found := c.entries[key]; found {
+		return mappings, nil
+	}
+
+	mappings, err := load(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove outdated versions of the file
+	for k := range c.entries {
+		if k.filename == key.filename && k.format == key.format {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = mappings
+
+	return mappings, nil
+}
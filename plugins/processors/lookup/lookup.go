@@ -3,31 +3,103 @@ package lookup
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	lru "github.com/hashicorp/golang-lru/v2"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/plugins/common/aws"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 //go:embed sample.conf
 var sampleConfig string
 
+// dropMarker is the reserved tag marking keys whose metrics should be dropped
+var dropMarker = telegraf.Tag{Key: "__drop__", Value: "true"}
+
 type Processor struct {
-	Filenames   []string        `toml:"files"`
-	Fileformat  string          `toml:"format"`
-	KeyTemplate string          `toml:"key"`
-	Log         telegraf.Logger `toml:"-"`
+	Filenames       []string          `toml:"files"`
+	AllowEmpty      bool              `toml:"allow_empty"`
+	Fileformat      string            `toml:"format"`
+	CSVKeyColumn    string            `toml:"csv_key_column"`
+	CSVValueColumns []string          `toml:"csv_value_columns"`
+	JSONKeyField    string            `toml:"json_key_field"`
+	KeyTemplate     string            `toml:"key"`
+	MaxErrors       int               `toml:"max_errors"`
+	SharedCache     bool              `toml:"shared_cache"`
+	DropMarked      bool              `toml:"drop_marked"`
+	CaseInsensitive bool              `toml:"case_insensitive"`
+	Overwrite       bool              `toml:"overwrite"`
+	TagsInclude     []string          `toml:"tags_include"`
+	TagsExclude     []string          `toml:"tags_exclude"`
+	FallbackFromTag map[string]string `toml:"fallback_from_tag"`
+	DefaultKey      string            `toml:"default_key"`
+	DefaultTags     map[string]string `toml:"default_tags"`
+	ValueTemplates  map[string]string `toml:"value_templates"`
+	Fields          []string          `toml:"fields"`
+	FieldTypes      map[string]string `toml:"field_types"`
+	RefreshInterval config.Duration   `toml:"refresh_interval"`
+	CacheSize       int               `toml:"cache_size"`
+	ReportUnmatched config.Duration   `toml:"report_unmatched_interval"`
+	MaxUnmatched    int               `toml:"report_unmatched_max_keys"`
+	Log             telegraf.Logger   `toml:"-"`
+	internalaws.CredentialConfig
+	httpconfig.HTTPClientConfig
+
+	client      *http.Client
+	s3Client    *s3.Client
+	tmpl        *template.Template
+	keyCache    *lru.Cache[uint64, string]
+	valueTmpl   map[string]*template.Template
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+	mappings    map[string][]telegraf.Tag
+	defaultTags []telegraf.Tag
+	format      string
+	load        func(string) (map[string][]telegraf.Tag, error)
+	fileStates  map[string]fileState
+	total       selfstat.Stat
+	unmatched   map[string]int
+	overflow    int
+	lastReport  time.Time
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+
+	// Protects the mappings and unmatched keys shared with the background
+	// refresh and reporting
+	sync.Mutex
+}
 
-	tmpl     *template.Template
-	mappings map[string][]telegraf.Tag
+// fileState is used to detect changes of the mapping files. For remote
+// sources the entity tag is used if provided by the server.
+type fileState struct {
+	modtime time.Time
+	size    int64
+	etag    string
 }
 
 func (*Processor) SampleConfig() string {
@@ -43,26 +115,297 @@ func (p *Processor) Init() error {
 		return errors.New("missing 'key_template'")
 	}
 
-	tmpl, err := template.New("key").Parse(p.KeyTemplate)
+	tmpl, err := template.New("key").Funcs(sprig.TxtFuncMap()).Parse(p.KeyTemplate)
 	if err != nil {
 		return fmt.Errorf("creating template failed: %w", err)
 	}
 	p.tmpl = tmpl
 
-	p.mappings = make(map[string][]telegraf.Tag)
-	switch strings.ToLower(p.Fileformat) {
+	if p.CacheSize > 0 && !seriesOnly(tmpl) {
+		p.Log.Warn("Disabling key cache as the key template uses fields or the timestamp")
+	} else if p.CacheSize > 0 {
+		keyCache, err := lru.New[uint64, string](p.CacheSize)
+		if err != nil {
+			return fmt.Errorf("creating key cache failed: %w", err)
+		}
+		p.keyCache = keyCache
+	}
+
+	p.valueTmpl = make(map[string]*template.Template, len(p.ValueTemplates))
+	for key, vt := range p.ValueTemplates {
+		t, err := template.New(key).Funcs(sprig.TxtFuncMap()).Parse(vt)
+		if err != nil {
+			return fmt.Errorf("creating value template for %q failed: %w", key, err)
+		}
+		p.valueTmpl[key] = t
+	}
+
+	tagFilter, err := filter.NewIncludeExcludeFilter(p.TagsInclude, p.TagsExclude)
+	if err != nil {
+		return fmt.Errorf("creating tag filter failed: %w", err)
+	}
+	p.tagFilter = tagFilter
+
+	for k, v := range p.DefaultTags {
+		p.defaultTags = append(p.defaultTags, telegraf.Tag{Key: k, Value: v})
+	}
+
+	if len(p.Fields) > 0 {
+		fieldFilter, err := filter.Compile(p.Fields)
+		if err != nil {
+			return fmt.Errorf("creating field filter failed: %w", err)
+		}
+		p.fieldFilter = fieldFilter
+	}
+	for key, t := range p.FieldTypes {
+		switch t {
+		case "", "int", "uint", "float", "bool", "string":
+		default:
+			return fmt.Errorf("invalid type %q for field %q", t, key)
+		}
+	}
+
+	format := strings.ToLower(p.Fileformat)
+	var load func(string) (map[string][]telegraf.Tag, error)
+	switch format {
 	case "", "json":
-		return p.loadJSONFiles()
+		load = p.loadJSONFile
+	case "json_array":
+		if p.JSONKeyField == "" {
+			return errors.New("missing 'json_key_field' for format \"json_array\"")
+		}
+		load = p.loadJSONArrayFile
+		format += ":" + p.JSONKeyField
 	case "csv_key_name_value":
-		return p.loadCSVKeyNameValueFiles()
+		load = func(fn string) (map[string][]telegraf.Tag, error) {
+			return p.loadKeyNameValueFile(fn, ',')
+		}
+	case "tsv_key_name_value":
+		load = func(fn string) (map[string][]telegraf.Tag, error) {
+			return p.loadKeyNameValueFile(fn, '\t')
+		}
 	case "csv_key_values":
-		return p.loadCSVKeyValuesFiles()
+		load = p.loadCSVKeyValuesFile
+	case "csv":
+		load = p.loadCSVFile
+		// The content depends on the column settings so make sure to not
+		// share files loaded with different settings
+		format += ":" + p.CSVKeyColumn + ":" + strings.Join(p.CSVValueColumns, ",")
+	default:
+		return fmt.Errorf("invalid format %q, must be one of json, json_array, csv, csv_key_name_value, csv_key_values or tsv_key_name_value", p.Fileformat)
+	}
+
+	p.format = format
+	p.load = load
+
+	if err := p.initRemote(); err != nil {
+		return err
+	}
+
+	files, err := p.expandFiles()
+	if err != nil {
+		return err
+	}
+	mappings, states, err := p.loadMappings(files)
+	if err != nil {
+		return err
+	}
+	p.mappings = mappings
+	p.fileStates = states
+	p.total = selfstat.Register("lookup", "mappings_total", map[string]string{"files": strings.Join(p.Filenames, ",")})
+	p.total.Set(int64(len(mappings)))
+	p.Log.Infof("Loaded %d mapping keys in total", len(p.mappings))
+
+	p.unmatched = make(map[string]int)
+	p.lastReport = time.Now()
+
+	return nil
+}
+
+// expandFiles resolves glob patterns and directories in the configured files
+// to the list of files to load. Remote sources are used as-is.
+func (p *Processor) expandFiles() ([]string, error) {
+	files := make([]string, 0, len(p.Filenames))
+	for _, pattern := range p.Filenames {
+		if isRemote(pattern) {
+			files = append(files, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			// Keep plain filenames to report the error when loading the file
+			if !strings.ContainsAny(pattern, "*?[") {
+				files = append(files, pattern)
+				continue
+			}
+			if !p.AllowEmpty {
+				return nil, fmt.Errorf("pattern %q does not match any file", pattern)
+			}
+		}
+
+		for _, fn := range matches {
+			info, err := os.Stat(fn)
+			if err != nil {
+				return nil, fmt.Errorf("accessing %q failed: %w", fn, err)
+			}
+			if !info.IsDir() {
+				files = append(files, fn)
+				continue
+			}
+
+			entries, err := os.ReadDir(fn)
+			if err != nil {
+				return nil, fmt.Errorf("reading directory %q failed: %w", fn, err)
+			}
+			for _, entry := range entries {
+				if entry.Type().IsRegular() {
+					files = append(files, filepath.Join(fn, entry.Name()))
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// loadMappings loads the given files and merges their mappings
+func (p *Processor) loadMappings(files []string) (map[string][]telegraf.Tag, map[string]fileState, error) {
+	var mappings map[string][]telegraf.Tag
+	states := make(map[string]fileState, len(files))
+	for _, fn := range files {
+		// Remember the state before loading to not miss changes in between.
+		// Remote sources without state are always reloaded.
+		if state, err := p.stat(fn); err == nil && state != (fileState{}) {
+			states[fn] = state
+		}
+
+		var m map[string][]telegraf.Tag
+		var err error
+		if p.SharedCache && !isRemote(fn) {
+			m, err = sharedFiles.load(fn, p.format, p.load)
+		} else {
+			m, err = p.load(fn)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		p.reportLoaded(fn, len(m))
+
+		// Use the mapping of a single file as-is to avoid copying the data
+		// which is important for sharing the content across instances.
+		if len(files) == 1 && !p.CaseInsensitive {
+			mappings = m
+			break
+		}
+		if mappings == nil {
+			mappings = make(map[string][]telegraf.Tag, len(m))
+		}
+		for key, tags := range m {
+			key = p.normalizeKey(key)
+			mappings[key] = append(mappings[key], tags...)
+		}
 	}
 
-	return fmt.Errorf("invalid format %q", p.Fileformat)
+	return mappings, states, nil
 }
 
+func (p *Processor) Start(telegraf.Accumulator) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	if p.RefreshInterval > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.runPeriodically(ctx, time.Duration(p.RefreshInterval), p.refresh)
+		}()
+	}
+	if p.ReportUnmatched > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.runPeriodically(ctx, time.Duration(p.ReportUnmatched), p.reportUnmatched)
+		}()
+	}
+
+	return nil
+}
+
+func (p *Processor) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	for _, out := range p.Apply(m) {
+		acc.AddMetric(out)
+	}
+	return nil
+}
+
+func (p *Processor) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+}
+
+// runPeriodically calls the given function in the given interval until the
+// context is cancelled.
+func (p *Processor) runPeriodically(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+// refresh reloads the mapping files if any of them changed or files were added
+// or removed. In case of errors the previous mappings are kept. The file states
+// are only accessed by the background refresh after initialization.
+func (p *Processor) refresh() {
+	files, err := p.expandFiles()
+	if err != nil {
+		p.Log.Errorf("Reloading files failed, keeping previous mappings: %v", err)
+		return
+	}
+
+	changed := len(files) != len(p.fileStates)
+	for _, fn := range files {
+		previous, found := p.fileStates[fn]
+		state, err := p.stat(fn)
+		if !found || err != nil || state != previous {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+
+	mappings, states, err := p.loadMappings(files)
+	if err != nil {
+		p.Log.Errorf("Reloading files failed, keeping previous mappings: %v", err)
+		return
+	}
+	p.fileStates = states
+	p.Lock()
+	p.mappings = mappings
+	p.Unlock()
+	p.total.Set(int64(len(mappings)))
+	p.Log.Infof("Reloaded %d mapping keys in total", len(mappings))
+}
+
+// Apply resolves the metrics using the current mappings
 func (p *Processor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	mappings := p.mappings
+	p.Unlock()
+
 	out := make([]telegraf.Metric, 0, len(in))
 	for _, raw := range in {
 		m := raw
@@ -70,58 +413,376 @@ func (p *Processor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 			m = wm.Unwrap()
 		}
 
-		var buf bytes.Buffer
-		if err := p.tmpl.Execute(&buf, m); err != nil {
+		key, err := p.generateKey(m)
+		if err != nil {
 			p.Log.Errorf("generating key failed: %v", err)
 			p.Log.Debugf("metric was %v", m)
-		} else if tags, found := p.mappings[buf.String()]; found {
-			for _, tag := range tags {
-				m.AddTag(tag.Key, tag.Value)
-			}
+			out = append(out, raw)
+			continue
+		}
+
+		tags, found := mappings[key]
+		if !found {
+			p.recordUnmatched(key)
+			tags = p.defaultMapping(mappings)
+		}
+		if p.DropMarked && slices.Contains(tags, dropMarker) {
+			raw.Drop()
+			continue
+		}
+		p.applyMapping(m, tags)
+		if !found {
+			p.applyFallback(m)
 		}
 		out = append(out, raw)
 	}
 	return out
 }
 
-func (p *Processor) loadJSONFiles() error {
-	for _, fn := range p.Filenames {
-		buf, err := os.ReadFile(fn)
-		if err != nil {
-			return fmt.Errorf("loading %q failed: %w", fn, err)
+// generateKey renders the key template for the given metric. If the cache is
+// enabled, keys are cached by the series, i.e. the name and tags, of the metric.
+func (p *Processor) generateKey(m telegraf.Metric) (string, error) {
+	var id uint64
+	if p.keyCache != nil {
+		id = m.HashID()
+		if key, found := p.keyCache.Get(id); found {
+			return key, nil
 		}
+	}
 
-		var data map[string]map[string]string
-		if err := json.Unmarshal(buf, &data); err != nil {
-			return fmt.Errorf("parsing %q failed: %w", fn, err)
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, m); err != nil {
+		return "", err
+	}
+	key := p.normalizeKey(buf.String())
+
+	if p.keyCache != nil {
+		p.keyCache.Add(id, key)
+	}
+	return key, nil
+}
+
+// seriesOnly checks if the template only depends on the series, i.e. the name
+// and tags, of the metric. Templates passing the metric as a whole, e.g. to a
+// function, are assumed to depend on all data.
+func seriesOnly(tmpl *template.Template) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && !seriesOnlyNode(t.Tree.Root) {
+			return false
 		}
+	}
+	return true
+}
 
-		for key, tags := range data {
-			for k, v := range tags {
-				p.mappings[key] = append(p.mappings[key], telegraf.Tag{Key: k, Value: v})
+func seriesOnlyNode(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return true
+		}
+		for _, child := range n.Nodes {
+			if !seriesOnlyNode(child) {
+				return false
+			}
+		}
+	case *parse.ActionNode:
+		return seriesOnlyNode(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return true
+		}
+		for _, cmd := range n.Cmds {
+			if !seriesOnlyNode(cmd) {
+				return false
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if !seriesOnlyNode(arg) {
+				return false
 			}
 		}
+	case *parse.IfNode:
+		return seriesOnlyNode(n.Pipe) && seriesOnlyNode(n.List) && seriesOnlyNode(n.ElseList)
+	case *parse.RangeNode:
+		return seriesOnlyNode(n.Pipe) && seriesOnlyNode(n.List) && seriesOnlyNode(n.ElseList)
+	case *parse.WithNode:
+		return seriesOnlyNode(n.Pipe) && seriesOnlyNode(n.List) && seriesOnlyNode(n.ElseList)
+	case *parse.TemplateNode:
+		return seriesOnlyNode(n.Pipe)
+	case *parse.ChainNode:
+		return seriesOnlyNode(n.Node) && seriesOnlyIdent(n.Field)
+	case *parse.FieldNode:
+		return seriesOnlyIdent(n.Ident)
+	case *parse.VariableNode:
+		if len(n.Ident) == 1 && n.Ident[0] == "$" {
+			return false
+		}
+		return seriesOnlyIdent(n.Ident[1:])
+	case *parse.DotNode:
+		return false
 	}
-	return nil
+	return true
+}
+
+func seriesOnlyIdent(ident []string) bool {
+	if len(ident) == 0 {
+		return true
+	}
+	switch ident[0] {
+	case "Fields", "FieldList", "Field", "GetField", "HasField", "Time":
+		return false
+	}
+	return true
+}
+
+// recordUnmatched counts the occurrences of keys without mapping if reporting
+// is enabled. Keys exceeding the maximum number of distinct keys are only
+// counted in total.
+func (p *Processor) recordUnmatched(key string) {
+	if p.ReportUnmatched <= 0 {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if _, found := p.unmatched[key]; !found && len(p.unmatched) >= p.MaxUnmatched {
+		p.overflow++
+		return
+	}
+	p.unmatched[key]++
+}
+
+// reportUnmatched logs the keys without mapping seen since the last report
+// ordered by their number of occurrences and resets the counters.
+func (p *Processor) reportUnmatched() {
+	p.Lock()
+	unmatched, overflow := p.unmatched, p.overflow
+	p.unmatched = make(map[string]int)
+	p.overflow = 0
+	p.Unlock()
+
+	interval := time.Since(p.lastReport).Round(time.Second)
+	p.lastReport = time.Now()
+	if len(unmatched) == 0 && overflow == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(unmatched))
+	for k := range unmatched {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if c := unmatched[b] - unmatched[a]; c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	entries := make([]string, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, fmt.Sprintf("%q (%d)", k, unmatched[k]))
+	}
+	msg := fmt.Sprintf("Keys without mapping in the last %s: %s", interval, strings.Join(entries, ", "))
+	if overflow > 0 {
+		msg += fmt.Sprintf(" and %d occurrences of other keys", overflow)
+	}
+	p.Log.Info(msg)
 }
 
-func (p *Processor) loadCSVKeyNameValueFiles() error {
-	for _, fn := range p.Filenames {
-		if err := p.loadCSVKeyNameValueFile(fn); err != nil {
-			return err
+// defaultMapping returns the mapping to use for keys not found in the files.
+// The entry of the default key takes precedence over the default tags.
+func (p *Processor) defaultMapping(mappings map[string][]telegraf.Tag) []telegraf.Tag {
+	if p.DefaultKey != "" {
+		if tags, found := mappings[p.normalizeKey(p.DefaultKey)]; found {
+			return tags
 		}
 	}
-	return nil
+	return p.defaultTags
+}
+
+// applyMapping adds the given mapping entries to the metric either as tags or,
+// if selected, as fields.
+func (p *Processor) applyMapping(m telegraf.Metric, tags []telegraf.Tag) {
+	for _, tag := range tags {
+		if p.fieldFilter != nil && p.fieldFilter.Match(tag.Key) {
+			if !p.Overwrite && m.HasField(tag.Key) {
+				continue
+			}
+			v, err := p.convertField(tag.Key, p.transformValue(tag))
+			if err != nil {
+				p.Log.Errorf("converting value for field %q failed: %v", tag.Key, err)
+				continue
+			}
+			m.AddField(tag.Key, v)
+			continue
+		}
+
+		if !p.tagFilter.Match(tag.Key) || (!p.Overwrite && m.HasTag(tag.Key)) {
+			continue
+		}
+		m.AddTag(tag.Key, p.transformValue(tag))
+	}
+}
+
+// convertField converts the value to the type configured for the field. If no
+// type is configured, the type is inferred from the value trying integer,
+// float and boolean before falling back to a string.
+func (p *Processor) convertField(key, value string) (interface{}, error) {
+	switch p.FieldTypes[key] {
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "uint":
+		return strconv.ParseUint(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "string":
+		return value, nil
+	}
+
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v, nil
+	}
+	return value, nil
+}
+
+// transformValue applies the value template configured for the tag, if any,
+// to the mapped value. On errors the mapped value is kept.
+func (p *Processor) transformValue(tag telegraf.Tag) string {
+	t, found := p.valueTmpl[tag.Key]
+	if !found {
+		return tag.Value
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, tag.Value); err != nil {
+		p.Log.Errorf("generating value for tag %q failed: %v", tag.Key, err)
+		return tag.Value
+	}
+	return buf.String()
+}
+
+// applyFallback sets the configured target tags from the source tags of the
+// metric for keys without mapping. Existing target tags are kept.
+func (p *Processor) applyFallback(m telegraf.Metric) {
+	for target, source := range p.FallbackFromTag {
+		if m.HasTag(target) {
+			continue
+		}
+		if v, found := m.GetTag(source); found {
+			m.AddTag(target, v)
+		}
+	}
+}
+
+func (p *Processor) normalizeKey(key string) string {
+	if p.CaseInsensitive {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+func (p *Processor) loadJSONFile(fn string) (map[string][]telegraf.Tag, error) {
+	f, err := p.open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q failed: %w", fn, err)
+	}
+
+	var data map[string]map[string]string
+	if err := json.Unmarshal(buf, &data); err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %w", fn, err)
+	}
+
+	mappings := make(map[string][]telegraf.Tag, len(data))
+	for key, tags := range data {
+		for k, v := range tags {
+			mappings[key] = append(mappings[key], telegraf.Tag{Key: k, Value: v})
+		}
+	}
+	return mappings, nil
+}
+
+func (p *Processor) loadJSONArrayFile(fn string) (map[string][]telegraf.Tag, error) {
+	f, err := p.open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q failed: %w", fn, err)
+	}
+
+	var data []map[string]interface{}
+	if err := json.Unmarshal(buf, &data); err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %w", fn, err)
+	}
+
+	parseErrors := p.newParseErrorCounter(fn)
+	mappings := make(map[string][]telegraf.Tag, len(data))
+	for i, record := range data {
+		raw, found := record[p.JSONKeyField]
+		if !found {
+			if err := parseErrors.add(fmt.Errorf("record %d in %q has no key field %q", i, fn, p.JSONKeyField)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key, err := internal.ToString(raw)
+		if err != nil {
+			if err := parseErrors.add(fmt.Errorf("key of record %d in %q is invalid: %w", i, fn, err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tags := make([]telegraf.Tag, 0, len(record)-1)
+		for k, rv := range record {
+			if k == p.JSONKeyField {
+				continue
+			}
+			v, err := internal.ToString(rv)
+			if err != nil {
+				err = fmt.Errorf("value of %q in record %d in %q is invalid: %w", k, i, fn, err)
+				if err := parseErrors.add(err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			tags = append(tags, telegraf.Tag{Key: k, Value: v})
+		}
+		mappings[key] = append(mappings[key], tags...)
+	}
+	return mappings, nil
 }
 
-func (p *Processor) loadCSVKeyNameValueFile(fn string) error {
-	f, err := os.Open(fn)
+func (p *Processor) loadKeyNameValueFile(fn string, comma rune) (map[string][]telegraf.Tag, error) {
+	f, err := p.open(fn)
 	if err != nil {
-		return fmt.Errorf("loading %q failed: %w", fn, err)
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
 	}
 	defer f.Close()
 
+	parseErrors := p.newParseErrorCounter(fn)
+	mappings := make(map[string][]telegraf.Tag)
+
 	reader := csv.NewReader(f)
+	reader.Comma = comma
 	reader.Comment = '#'
 	reader.FieldsPerRecord = -1
 	reader.TrimLeadingSpace = true
@@ -134,38 +795,38 @@ func (p *Processor) loadCSVKeyNameValueFile(fn string) error {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return fmt.Errorf("reading line %d in %q failed: %w", line, fn, err)
+			if err := parseErrors.add(fmt.Errorf("reading line %d in %q failed: %w", line, fn, err)); err != nil {
+				return nil, err
+			}
+			continue
 		}
 		if len(data) < 3 {
-			return fmt.Errorf("line %d in %q has not enough columns, requiring at least `key,name,value`", line, fn)
+			if err := parseErrors.add(fmt.Errorf("line %d in %q has not enough columns, requiring at least `key,name,value`", line, fn)); err != nil {
+				return nil, err
+			}
+			continue
 		}
 		if len(data)%2 != 1 {
-			return fmt.Errorf("line %d in %q has a tag-name without value", line, fn)
+			if err := parseErrors.add(fmt.Errorf("line %d in %q has a tag-name without value", line, fn)); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		key := data[0]
 		for i := 1; i < len(data)-1; i += 2 {
 			k, v := data[i], data[i+1]
-			p.mappings[key] = append(p.mappings[key], telegraf.Tag{Key: k, Value: v})
+			mappings[key] = append(mappings[key], telegraf.Tag{Key: k, Value: v})
 		}
 	}
 
-	return nil
+	return mappings, nil
 }
 
-func (p *Processor) loadCSVKeyValuesFiles() error {
-	for _, fn := range p.Filenames {
-		if err := p.loadCSVKeyValuesFile(fn); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (p *Processor) loadCSVKeyValuesFile(fn string) error {
-	f, err := os.Open(fn)
+func (p *Processor) loadCSVKeyValuesFile(fn string) (map[string][]telegraf.Tag, error) {
+	f, err := p.open(fn)
 	if err != nil {
-		return fmt.Errorf("loading %q failed: %w", fn, err)
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
 	}
 	defer f.Close()
 
@@ -177,15 +838,18 @@ func (p *Processor) loadCSVKeyValuesFile(fn string) error {
 	header, err := reader.Read()
 	if err != nil {
 		if errors.Is(err, io.EOF) {
-			return fmt.Errorf("missing header in %q", fn)
+			return nil, fmt.Errorf("missing header in %q", fn)
 		}
-		return fmt.Errorf("reading header in %q failed: %w", fn, err)
+		return nil, fmt.Errorf("reading header in %q failed: %w", fn, err)
 	}
 	if len(header) < 2 {
-		return fmt.Errorf("header in %q has not enough columns, requiring at least `key,value`", fn)
+		return nil, fmt.Errorf("header in %q has not enough columns, requiring at least `key,value`", fn)
 	}
 	header = header[1:]
 
+	parseErrors := p.newParseErrorCounter(fn)
+	mappings := make(map[string][]telegraf.Tag)
+
 	line := 1
 	for {
 		line++
@@ -194,22 +858,135 @@ func (p *Processor) loadCSVKeyValuesFile(fn string) error {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return fmt.Errorf("reading line %d in %q failed: %w", line, fn, err)
+			if err := parseErrors.add(fmt.Errorf("reading line %d in %q failed: %w", line, fn, err)); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		key := data[0]
 		for i, v := range data[1:] {
 			v = strings.TrimSpace(v)
 			if v != "" {
-				p.mappings[key] = append(p.mappings[key], telegraf.Tag{Key: header[i], Value: v})
+				mappings[key] = append(mappings[key], telegraf.Tag{Key: header[i], Value: v})
+			}
+		}
+	}
+
+	return mappings, nil
+}
+
+func (p *Processor) loadCSVFile(fn string) (map[string][]telegraf.Tag, error) {
+	f, err := p.open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	// Read the first line which should be the header
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("missing header in %q", fn)
+		}
+		return nil, fmt.Errorf("reading header in %q failed: %w", fn, err)
+	}
+	if len(header) < 2 {
+		return nil, fmt.Errorf("header in %q has not enough columns, requiring at least `key,value`", fn)
+	}
+
+	// Determine the key column and the value columns to use
+	keyIdx := 0
+	if p.CSVKeyColumn != "" {
+		keyIdx = slices.Index(header, p.CSVKeyColumn)
+		if keyIdx < 0 {
+			return nil, fmt.Errorf("key column %q not found in header of %q", p.CSVKeyColumn, fn)
+		}
+	}
+	var valueIdx []int
+	if len(p.CSVValueColumns) > 0 {
+		for _, name := range p.CSVValueColumns {
+			idx := slices.Index(header, name)
+			if idx < 0 {
+				return nil, fmt.Errorf("value column %q not found in header of %q", name, fn)
+			}
+			valueIdx = append(valueIdx, idx)
+		}
+	} else {
+		for idx := range header {
+			if idx != keyIdx {
+				valueIdx = append(valueIdx, idx)
+			}
+		}
+	}
+
+	parseErrors := p.newParseErrorCounter(fn)
+	mappings := make(map[string][]telegraf.Tag)
+
+	line := 1
+	for {
+		line++
+		data, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err := parseErrors.add(fmt.Errorf("reading line %d in %q failed: %w", line, fn, err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		key := data[keyIdx]
+		for _, idx := range valueIdx {
+			v := strings.TrimSpace(data[idx])
+			if v != "" {
+				mappings[key] = append(mappings[key], telegraf.Tag{Key: header[idx], Value: v})
 			}
 		}
 	}
 
+	return mappings, nil
+}
+
+func (p *Processor) reportLoaded(fn string, keys int) {
+	p.Log.Infof("Loaded %d mapping keys from %q", keys, fn)
+	selfstat.Register("lookup", "mappings", map[string]string{"file": fn}).Set(int64(keys))
+}
+
+type parseErrorCounter struct {
+	max   int
+	count int
+	stat  selfstat.Stat
+	log   telegraf.Logger
+}
+
+func (p *Processor) newParseErrorCounter(fn string) *parseErrorCounter {
+	return &parseErrorCounter{
+		max:  p.MaxErrors,
+		stat: selfstat.Register("lookup", "parse_errors", map[string]string{"file": fn}),
+		log:  p.Log,
+	}
+}
+
+// add records the given parse error and returns it in case the maximum number
+// of tolerated errors is exceeded.
+func (c *parseErrorCounter) add(err error) error {
+	c.count++
+	c.stat.Incr(1)
+	if c.count > c.max {
+		return err
+	}
+	c.log.Warnf("Skipping line: %v", err)
 	return nil
 }
+
 func init() {
-	processors.Add("lookup", func() telegraf.Processor {
-		return &Processor{}
+	processors.AddStreaming("lookup", func() telegraf.StreamingProcessor {
+		return &Processor{Overwrite: true, MaxUnmatched: 100}
 	})
 }
@@ -1,17 +1,25 @@
 package lookup
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/metric"
+	internalaws "github.com/influxdata/telegraf/plugins/common/aws"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -37,6 +45,432 @@ func TestInit(t *testing.T) {
 		KeyTemplate: "lala",
 	}
 	require.ErrorContains(t, plugin.Init(), "invalid format")
+
+	plugin = &Processor{
+		Filenames:    []string{"testcases/column_selection_csv/lut.csv"},
+		Fileformat:   "csv",
+		CSVKeyColumn: "name",
+		KeyTemplate:  "lala",
+		Log:          testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), `key column "name" not found`)
+
+	plugin = &Processor{
+		Filenames:   []string{"testcases/normal_lookup_json/lut.json"},
+		KeyTemplate: "lala",
+		Fields:      []string{"cabinet"},
+		FieldTypes:  map[string]string{"cabinet": "foo"},
+	}
+	require.ErrorContains(t, plugin.Init(), `invalid type "foo" for field "cabinet"`)
+
+	plugin = &Processor{
+		Filenames:   []string{"testcases/glob_json/other/*.json"},
+		KeyTemplate: "lala",
+		Log:         testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), `pattern "testcases/glob_json/other/*.json" does not match any file`)
+
+	plugin = &Processor{
+		Filenames:   []string{"testcases/normal_lookup_json_array/lut.json"},
+		Fileformat:  "json_array",
+		KeyTemplate: "lala",
+		Log:         testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), "missing 'json_key_field'")
+
+	plugin = &Processor{
+		Filenames:    []string{"testcases/normal_lookup_json_array/lut.json"},
+		Fileformat:   "json_array",
+		JSONKeyField: "host",
+		KeyTemplate:  "lala",
+		Log:          testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), `record 3 in "testcases/normal_lookup_json_array/lut.json" has no key field "host"`)
+}
+
+func TestKeyCacheSeriesOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		cached   bool
+	}{
+		{name: "name and tags", template: `{{.Name}}-{{.Tag "host" | lower}}`, cached: true},
+		{name: "conditional tag", template: `{{if .HasTag "host"}}{{.Tag "host"}}{{end}}`, cached: true},
+		{name: "field", template: `{{.Field "value"}}`},
+		{name: "fields", template: `{{index .Fields "value"}}`},
+		{name: "timestamp", template: `{{.Time.Unix}}`},
+		{name: "timestamp in branch", template: `{{if .HasTag "host"}}{{.Time}}{{end}}`},
+		{name: "variable", template: `{{$.Time}}`},
+		{name: "whole metric", template: `{{printf "%v" .}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Processor{
+				Filenames:   []string{"testcases/normal_lookup_json/lut.json"},
+				KeyTemplate: tt.template,
+				CacheSize:   10,
+				Log:         testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.Equal(t, tt.cached, plugin.keyCache != nil)
+		})
+	}
+}
+
+func TestMaxErrorsExceeded(t *testing.T) {
+	plugin := &Processor{
+		Filenames:   []string{"testcases/skip_malformed_lines_csv/lut.csv"},
+		Fileformat:  "csv_key_name_value",
+		KeyTemplate: `{{.Tag "host"}}`,
+		MaxErrors:   1,
+		Log:         testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), "line 3 in \"testcases/skip_malformed_lines_csv/lut.csv\" has a tag-name without value")
+}
+
+func TestLoadedMappingsStat(t *testing.T) {
+	fn := "testcases/normal_lookup_json/lut.json"
+	plugin := &Processor{
+		Filenames:   []string{fn},
+		KeyTemplate: `{{.Name}}-{{.Tag "host"}}`,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Len(t, plugin.mappings, 4)
+
+	stat := selfstat.Register("lookup", "mappings", map[string]string{"file": fn})
+	require.EqualValues(t, 4, stat.Get())
+}
+
+func TestTotalMappingsStat(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"a": {"x": "1"}, "b": {"x": "2"}}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"b": {"y": "3"}}`), 0600))
+
+	pattern := filepath.Join(dir, "*.json")
+	plugin := &Processor{
+		Filenames:       []string{pattern},
+		KeyTemplate:     `{{.Tag "key"}}`,
+		RefreshInterval: config.Duration(time.Hour),
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// Keys shared across files must only be counted once
+	stat := selfstat.Register("lookup", "mappings_total", map[string]string{"files": pattern})
+	require.EqualValues(t, 2, stat.Get())
+
+	// The total must be updated on reload
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.json"), []byte(`{"c": {"z": "4"}}`), 0600))
+	plugin.refresh()
+	require.EqualValues(t, 3, stat.Get())
+}
+
+func TestSharedCache(t *testing.T) {
+	fn := "testcases/normal_lookup_json/lut.json"
+	plugin1 := &Processor{
+		Filenames:   []string{fn},
+		KeyTemplate: `{{.Name}}-{{.Tag "host"}}`,
+		SharedCache: true,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin1.Init())
+
+	plugin2 := &Processor{
+		Filenames:   []string{fn},
+		KeyTemplate: `{{.Tag "host"}}`,
+		SharedCache: true,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin2.Init())
+
+	// Both instances should use the same underlying data
+	require.Len(t, plugin2.mappings, 4)
+	require.Equal(t, reflect.ValueOf(plugin1.mappings).Pointer(), reflect.ValueOf(plugin2.mappings).Pointer())
+
+	// Instances without shared cache should load their own copy
+	plugin3 := &Processor{
+		Filenames:   []string{fn},
+		KeyTemplate: `{{.Tag "host"}}`,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin3.Init())
+	require.Len(t, plugin3.mappings, len(plugin1.mappings))
+	require.NotEqual(t, reflect.ValueOf(plugin1.mappings).Pointer(), reflect.ValueOf(plugin3.mappings).Pointer())
+}
+
+func TestRefresh(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "lut.json")
+	require.NoError(t, os.WriteFile(fn, []byte(`{"a": {"value": "first"}}`), 0600))
+
+	plugin := &Processor{
+		Filenames:       []string{fn},
+		KeyTemplate:     `{{.Tag "key"}}`,
+		RefreshInterval: config.Duration(time.Hour),
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	input := metric.New("test", map[string]string{"key": "a"}, map[string]interface{}{"x": 1}, time.Unix(0, 0))
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{"key": "a", "value": "first"}, map[string]interface{}{"x": 1}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input.Copy()))
+
+	// Update the file and make sure the change is detected
+	require.NoError(t, os.WriteFile(fn, []byte(`{"a": {"value": "second"}}`), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+	plugin.refresh()
+	expected = []telegraf.Metric{
+		metric.New("test", map[string]string{"key": "a", "value": "second"}, map[string]interface{}{"x": 1}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input.Copy()))
+
+	// Broken files should keep the previous mappings
+	require.NoError(t, os.WriteFile(fn, []byte(`{"a": {`), 0600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+	plugin.refresh()
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input.Copy()))
+}
+
+func TestRefreshBackground(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "lut.json")
+	require.NoError(t, os.WriteFile(fn, []byte(`{"a": {"value": "first"}}`), 0600))
+
+	plugin := &Processor{
+		Filenames:       []string{fn},
+		KeyTemplate:     `{{.Tag "key"}}`,
+		RefreshInterval: config.Duration(10 * time.Millisecond),
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Update the file and make sure the change is picked up without
+	// processing any metric
+	require.NoError(t, os.WriteFile(fn, []byte(`{"a": {"value": "second"}}`), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+	require.Eventually(t, func() bool {
+		plugin.Lock()
+		defer plugin.Unlock()
+		return len(plugin.mappings["a"]) == 1 && plugin.mappings["a"][0].Value == "second"
+	}, time.Second, 10*time.Millisecond)
+
+	input := metric.New("test", map[string]string{"key": "a"}, map[string]interface{}{"x": 1}, time.Unix(0, 0))
+	require.NoError(t, plugin.Add(input, &acc))
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{"key": "a", "value": "second"}, map[string]interface{}{"x": 1}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestRefreshAddedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"a": {"value": "first"}}`), 0600))
+
+	plugin := &Processor{
+		Filenames:       []string{filepath.Join(dir, "*.json")},
+		KeyTemplate:     `{{.Tag "key"}}`,
+		RefreshInterval: config.Duration(time.Hour),
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Len(t, plugin.mappings, 1)
+
+	// Files matching the pattern should be picked up on refresh
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"b": {"value": "second"}}`), 0600))
+	plugin.refresh()
+	input := metric.New("test", map[string]string{"key": "b"}, map[string]interface{}{"x": 1}, time.Unix(0, 0))
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{"key": "b", "value": "second"}, map[string]interface{}{"x": 1}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, plugin.Apply(input))
+	require.Len(t, plugin.mappings, 2)
+}
+
+func TestReportUnmatched(t *testing.T) {
+	logger := &testutil.CaptureLogger{}
+	plugin := &Processor{
+		Filenames:       []string{"testcases/normal_lookup_json/lut.json"},
+		KeyTemplate:     `{{.Name}}-{{.Tag "host"}}`,
+		ReportUnmatched: config.Duration(time.Hour),
+		MaxUnmatched:    2,
+		Log:             logger,
+	}
+	require.NoError(t, plugin.Init())
+
+	var input []telegraf.Metric
+	for _, host := range []string{"Hugin", "Loki", "Odin", "Loki", "Freya", "Odin", "Loki"} {
+		input = append(input, metric.New("cpu", map[string]string{"host": host}, map[string]interface{}{"x": 1}, time.Unix(0, 0)))
+	}
+	plugin.Apply(input...)
+
+	logger.Clear()
+	plugin.reportUnmatched()
+
+	var infos []string
+	for _, m := range logger.Messages() {
+		if m.Level == testutil.LevelInfo {
+			infos = append(infos, m.Text)
+		}
+	}
+	require.Len(t, infos, 1)
+	require.Contains(t, infos[0], `"cpu-Loki" (3), "cpu-Odin" (2) and 1 occurrences of other keys`)
+
+	// Counters should be reset after reporting
+	require.Empty(t, plugin.unmatched)
+	require.Zero(t, plugin.overflow)
+}
+
+func TestReportUnmatchedBackground(t *testing.T) {
+	logger := &testutil.CaptureLogger{}
+	plugin := &Processor{
+		Filenames:       []string{"testcases/normal_lookup_json/lut.json"},
+		KeyTemplate:     `{{.Name}}-{{.Tag "host"}}`,
+		ReportUnmatched: config.Duration(10 * time.Millisecond),
+		MaxUnmatched:    2,
+		Log:             logger,
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	input := metric.New("cpu", map[string]string{"host": "Loki"}, map[string]interface{}{"x": 1}, time.Unix(0, 0))
+	require.NoError(t, plugin.Add(input, &acc))
+
+	// The report must be issued without further metrics flowing
+	require.Eventually(t, func() bool {
+		for _, m := range logger.Messages() {
+			if m.Level == testutil.LevelInfo && strings.Contains(m.Text, `"cpu-Loki" (1)`) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRemoteHTTP(t *testing.T) {
+	buf, err := os.ReadFile("testcases/normal_lookup_json/lut.json")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lut.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, err := w.Write(buf); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	plugin := &Processor{
+		Filenames:   []string{server.URL + "/lut.json"},
+		KeyTemplate: `{{.Name}}-{{.Tag "host"}}`,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Len(t, plugin.mappings, 4)
+
+	plugin = &Processor{
+		Filenames:   []string{server.URL + "/missing.json"},
+		KeyTemplate: `{{.Name}}-{{.Tag "host"}}`,
+		Log:         testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), "received status 404")
+}
+
+func TestRemoteHTTPRefresh(t *testing.T) {
+	var mu sync.Mutex
+	content, etag := `{"a": {"value": "first"}}`, `"v1"`
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("ETag", etag)
+		if r.Method == http.MethodHead {
+			return
+		}
+		downloads++
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	plugin := &Processor{
+		Filenames:       []string{server.URL + "/lut.json"},
+		KeyTemplate:     `{{.Tag "key"}}`,
+		RefreshInterval: config.Duration(time.Hour),
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// Unchanged sources must not be downloaded again
+	plugin.refresh()
+	plugin.refresh()
+	mu.Lock()
+	require.Equal(t, 1, downloads)
+	content, etag = `{"a": {"value": "second"}}`, `"v2"`
+	mu.Unlock()
+
+	// Changed sources must be reloaded
+	plugin.refresh()
+	mu.Lock()
+	require.Equal(t, 2, downloads)
+	mu.Unlock()
+	require.Equal(t, "second", plugin.mappings["a"][0].Value)
+}
+
+func TestRemoteS3(t *testing.T) {
+	buf, err := os.ReadFile("testcases/normal_lookup_json/lut.json")
+	require.NoError(t, err)
+
+	var downloads atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inventory/lut.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method == http.MethodHead {
+			return
+		}
+		downloads.Add(1)
+		if _, err := w.Write(buf); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	plugin := &Processor{
+		Filenames:       []string{"s3://inventory/lut.json"},
+		KeyTemplate:     `{{.Name}}-{{.Tag "host"}}`,
+		RefreshInterval: config.Duration(time.Hour),
+		CredentialConfig: internalaws.CredentialConfig{
+			Region:      "us-east-1",
+			AccessKey:   "dummy",
+			SecretKey:   "dummy",
+			EndpointURL: server.URL,
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Len(t, plugin.mappings, 4)
+
+	// Unchanged objects must not be downloaded again
+	plugin.refresh()
+	require.EqualValues(t, 1, downloads.Load())
 }
 
 func TestCases(t *testing.T) {
@@ -48,8 +482,8 @@ func TestCases(t *testing.T) {
 	require.NotEmpty(t, folders)
 
 	// Set up for file inputs
-	processors.Add("lookup", func() telegraf.Processor {
-		return &Processor{Log: testutil.Logger{}}
+	processors.AddStreaming("lookup", func() telegraf.StreamingProcessor {
+		return &Processor{Overwrite: true, Log: testutil.Logger{}}
 	})
 
 	for _, f := range folders {
@@ -84,8 +518,7 @@ func TestCases(t *testing.T) {
 			require.NoError(t, cfg.LoadConfig(configFilename))
 			require.Len(t, cfg.Processors, 1, "wrong number of processors")
 
-			proc := cfg.Processors[0].Processor.(processors.HasUnwrap)
-			plugin := proc.Unwrap().(*Processor)
+			plugin := cfg.Processors[0].Processor.(*Processor)
 			require.NoError(t, plugin.Init())
 
 			// Process expected metrics and compare with resulting metrics
@@ -104,8 +537,8 @@ func TestCasesTracking(t *testing.T) {
 	require.NotEmpty(t, folders)
 
 	// Set up for file inputs
-	processors.Add("lookup", func() telegraf.Processor {
-		return &Processor{Log: testutil.Logger{}}
+	processors.AddStreaming("lookup", func() telegraf.StreamingProcessor {
+		return &Processor{Overwrite: true, Log: testutil.Logger{}}
 	})
 
 	for _, f := range folders {
@@ -153,8 +586,7 @@ func TestCasesTracking(t *testing.T) {
 			require.NoError(t, cfg.LoadConfig(configFilename))
 			require.Len(t, cfg.Processors, 1, "wrong number of processors")
 
-			proc := cfg.Processors[0].Processor.(processors.HasUnwrap)
-			plugin := proc.Unwrap().(*Processor)
+			plugin := cfg.Processors[0].Processor.(*Processor)
 			require.NoError(t, plugin.Init())
 
 			// Process expected metrics and compare with resulting metrics
@@ -175,3 +607,29 @@ func TestCasesTracking(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkApply(b *testing.B) {
+	for _, size := range []int{0, 100} {
+		b.Run(fmt.Sprintf("cache_size=%d", size), func(b *testing.B) {
+			plugin := &Processor{
+				Filenames:   []string{"testcases/normal_lookup_json/lut.json"},
+				KeyTemplate: `{{.Name}}-{{.Tag "host" | lower | title}}`,
+				CacheSize:   size,
+				Log:         testutil.Logger{},
+			}
+			require.NoError(b, plugin.Init())
+
+			m := metric.New(
+				"cpu",
+				map[string]string{"cpu": "cpu-total", "host": "thor"},
+				map[string]interface{}{"usage_idle": 99.75},
+				time.Unix(0, 0),
+			)
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				plugin.Apply(m.Copy())
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// isRemote returns true if the given file refers to a remote source instead
+// of a local file
+func isRemote(fn string) bool {
+	u, err := url.Parse(fn)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "s3"
+}
+
+// initRemote creates the clients required for accessing the remote sources
+func (p *Processor) initRemote() error {
+	if !slices.ContainsFunc(p.Filenames, isRemote) {
+		return nil
+	}
+
+	client, err := p.HTTPClientConfig.CreateClient(context.Background(), p.Log)
+	if err != nil {
+		return fmt.Errorf("creating HTTP client failed: %w", err)
+	}
+	p.client = client
+
+	if !slices.ContainsFunc(p.Filenames, func(fn string) bool { return strings.HasPrefix(fn, "s3://") }) {
+		return nil
+	}
+
+	cfg, err := p.CredentialConfig.Credentials()
+	if err != nil {
+		return fmt.Errorf("getting AWS credentials failed: %w", err)
+	}
+	p.s3Client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.HTTPClient = p.client
+		if p.EndpointURL != "" {
+			o.BaseEndpoint = aws.String(p.EndpointURL)
+			o.UsePathStyle = true
+		}
+	})
+
+	return nil
+}
+
+// timeout returns the timeout for requests to remote sources
+func (p *Processor) timeout() time.Duration {
+	if p.HTTPClientConfig.Timeout > 0 {
+		return time.Duration(p.HTTPClientConfig.Timeout)
+	}
+	return 5 * time.Second
+}
+
+// s3Location splits the given URL into bucket and key
+func s3Location(u *url.URL) (bucket, key string, err error) {
+	key = strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return "", "", errors.New("invalid S3 location, expecting s3://<bucket>/<key>")
+	}
+	return u.Host, key, nil
+}
+
+// stat returns the state of the given local file or remote source used to
+// detect changes. An empty state is returned for remote sources not providing
+// an entity tag or modification time.
+func (p *Processor) stat(fn string) (fileState, error) {
+	if !isRemote(fn) {
+		info, err := os.Stat(fn)
+		if err != nil {
+			return fileState{}, err
+		}
+		return fileState{modtime: info.ModTime(), size: info.Size()}, nil
+	}
+
+	u, err := url.Parse(fn)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	if u.Scheme == "s3" {
+		bucket, key, err := s3Location(u)
+		if err != nil {
+			return fileState{}, err
+		}
+		resp, err := p.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fileState{}, err
+		}
+		var state fileState
+		if resp.ETag != nil {
+			state.etag = *resp.ETag
+		}
+		if resp.LastModified != nil {
+			state.modtime = *resp.LastModified
+		}
+		if resp.ContentLength != nil {
+			state.size = *resp.ContentLength
+		}
+		return state, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fn, nil)
+	if err != nil {
+		return fileState{}, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fileState{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fileState{}, fmt.Errorf("received status %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	state := fileState{etag: resp.Header.Get("ETag")}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if t, err := http.ParseTime(lm); err == nil {
+			state.modtime = t
+		}
+	}
+	if state.etag == "" && state.modtime.IsZero() {
+		return fileState{}, nil
+	}
+	state.size = resp.ContentLength
+	return state, nil
+}
+
+// open returns a reader for the given local file or remote source
+func (p *Processor) open(fn string) (io.ReadCloser, error) {
+	if !isRemote(fn) {
+		return os.Open(fn)
+	}
+
+	u, err := url.Parse(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	// The context must stay valid while reading the body so cancel it once
+	// the body is closed.
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+
+	if u.Scheme == "s3" {
+		bucket, key, err := s3Location(u)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp, err := p.s3Client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fn, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("received status %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// cancelReadCloser cancels the request context when closing the body
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
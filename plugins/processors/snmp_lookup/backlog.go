@@ -15,8 +15,10 @@ type backlogEntry struct {
 }
 
 type backlog struct {
-	elements *list.List
-	ordered  bool
+	elements    *list.List
+	ordered     bool
+	failureTag  string
+	defaultTags map[string]string
 
 	acc telegraf.Accumulator
 	log telegraf.Logger
@@ -64,6 +66,13 @@ func (b *backlog) push(agent, index string, m telegraf.Metric) {
 	_ = b.elements.PushBack(e)
 }
 
+// emit adds the given metric, e.g. a diagnostic, bypassing the backlog
+func (b *backlog) emit(m telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+	b.acc.AddMetric(m)
+}
+
 func (b *backlog) resolve(agent string, tm *tagMap) {
 	b.Lock()
 	defer b.Unlock()
@@ -83,6 +92,21 @@ func (b *backlog) resolve(agent string, tm *tagMap) {
 				}
 			} else {
 				b.log.Warnf("Cannot resolve metrics because index %q not found for agent %q!", entry.index, agent)
+				for k, v := range b.defaultTags {
+					if !entry.metric.HasTag(k) {
+						entry.metric.AddTag(k, v)
+					}
+				}
+				if b.failureTag != "" {
+					reason := "index_not_found"
+					if tm.failed {
+						reason = "agent_error"
+					}
+					entry.metric.AddTag(b.failureTag, reason)
+				}
+			}
+			for k, v := range tm.fields[entry.index] {
+				entry.metric.AddField(k, v)
 			}
 			entry.resolved = true
 		}
@@ -0,0 +1,78 @@
+package snmp_lookup
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/snmp"
+)
+
+// credentials holds the SNMP settings for agents matching one of the agent
+// patterns. Empty settings are inherited from the plugin configuration.
+type credentials struct {
+	Agents       []string      `toml:"agents"`
+	Version      uint8         `toml:"version"`
+	Community    string        `toml:"community"`
+	ContextName  string        `toml:"context_name"`
+	SecLevel     string        `toml:"sec_level"`
+	SecName      string        `toml:"sec_name"`
+	AuthProtocol string        `toml:"auth_protocol"`
+	AuthPassword config.Secret `toml:"auth_password"`
+	PrivProtocol string        `toml:"priv_protocol"`
+	PrivPassword config.Secret `toml:"priv_password"`
+
+	filter filter.Filter
+}
+
+func (c *credentials) init(base snmp.ClientConfig) error {
+	if len(c.Agents) == 0 {
+		return fmt.Errorf("missing agents for credentials of %q", c.SecName)
+	}
+
+	f, err := filter.Compile(c.Agents)
+	if err != nil {
+		return fmt.Errorf("compiling agent patterns failed: %w", err)
+	}
+	c.filter = f
+
+	// Check the resulting configuration
+	if _, err := snmp.NewWrapper(c.apply(base)); err != nil {
+		return fmt.Errorf("parsing credentials for %v: %w", c.Agents, err)
+	}
+
+	return nil
+}
+
+// apply returns the given configuration with the settings overridden by the
+// non-empty credential settings.
+func (c *credentials) apply(cfg snmp.ClientConfig) snmp.ClientConfig {
+	if c.Version != 0 {
+		cfg.Version = c.Version
+	}
+	if c.Community != "" {
+		cfg.Community = c.Community
+	}
+	if c.ContextName != "" {
+		cfg.ContextName = c.ContextName
+	}
+	if c.SecLevel != "" {
+		cfg.SecLevel = c.SecLevel
+	}
+	if c.SecName != "" {
+		cfg.SecName = c.SecName
+	}
+	if c.AuthProtocol != "" {
+		cfg.AuthProtocol = c.AuthProtocol
+	}
+	if !c.AuthPassword.Empty() {
+		cfg.AuthPassword = c.AuthPassword
+	}
+	if c.PrivProtocol != "" {
+		cfg.PrivProtocol = c.PrivProtocol
+	}
+	if !c.PrivPassword.Empty() {
+		cfg.PrivPassword = c.PrivPassword
+	}
+	return cfg
+}
@@ -3,43 +3,91 @@ package snmp_lookup
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gosnmp/gosnmp"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/limiter"
 	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 //go:embed sample.conf
 var sampleConfig string
 
 type tagMapRows map[string]map[string]string
+type fieldMapRows map[string]map[string]interface{}
 type tagMap struct {
 	created time.Time
+	ttl     time.Duration
 	rows    tagMapRows
+	fields  fieldMapRows
+	failed  bool
+}
+
+type agentStats struct {
+	hits       selfstat.Stat
+	misses     selfstat.Stat
+	inflight   selfstat.Stat
+	walkErrors selfstat.Stat
+}
+
+type tagDefinition struct {
+	snmp.Field
+	Destinations []string `toml:"destinations"`
+
+	asTag   bool
+	asField bool
 }
 
 type Lookup struct {
-	AgentTag string       `toml:"agent_tag"`
-	IndexTag string       `toml:"index_tag"`
-	Tags     []snmp.Field `toml:"tag"`
+	AgentTag     string          `toml:"agent_tag"`
+	DefaultAgent string          `toml:"default_agent"`
+	IndexTag     string          `toml:"index_tag"`
+	IndexTags    []string        `toml:"index_tags"`
+	IndexType    string          `toml:"index_type"`
+	IndexOffset  int             `toml:"index_offset"`
+	Tags         []tagDefinition `toml:"tag"`
+	TagsFile     string          `toml:"tags_file"`
 
 	snmp.ClientConfig
 
-	CacheSize             int             `toml:"max_cache_entries"`
-	ParallelLookups       int             `toml:"max_parallel_lookups"`
-	Ordered               bool            `toml:"ordered"`
-	CacheTTL              config.Duration `toml:"cache_ttl"`
-	MinTimeBetweenUpdates config.Duration `toml:"min_time_between_updates"`
+	CacheSize             int               `toml:"max_cache_entries"`
+	ParallelLookups       int               `toml:"max_parallel_lookups"`
+	Ordered               bool              `toml:"ordered"`
+	CacheTTL              config.Duration   `toml:"cache_ttl"`
+	CacheTTLJitter        config.Duration   `toml:"cache_ttl_jitter"`
+	MinTimeBetweenUpdates config.Duration   `toml:"min_time_between_updates"`
+	MinRefreshInterval    config.Duration   `toml:"min_refresh_interval"`
+	SecondaryAgents       map[string]string `toml:"secondary_agents"`
+	FailureTag            string            `toml:"failure_tag"`
+	DefaultTags           map[string]string `toml:"default_tags"`
+	Credentials           []credentials     `toml:"credentials"`
+	EmitErrors            bool              `toml:"emit_errors"`
+	MaxRequestsPerSecond  int               `toml:"max_requests_per_second"`
 
 	Log telegraf.Logger `toml:"-"`
 
 	table             snmp.Table
 	cache             *store
 	backlog           *backlog
+	evictions         selfstat.Stat
+	stats             map[string]*agentStats
 	getConnectionFunc func(string) (snmp.Connection, error)
+	limiter           *limiter.RateLimiter
+
+	// Agents currently served by their secondary agent
+	failedOver map[string]bool
+	sync.Mutex
 }
 
 const (
@@ -65,26 +113,108 @@ func (l *Lookup) Init() (err error) {
 		return fmt.Errorf("loading translator: %w", err)
 	}
 
+	if l.IndexOffset < 0 {
+		return errors.New("index offset must not be negative")
+	}
+
+	// Check the index encoding
+	switch l.IndexType {
+	case "":
+		l.IndexType = "raw"
+	case "raw", "ipv4":
+	default:
+		return fmt.Errorf("invalid index type %q", l.IndexType)
+	}
+
+	// Check the per-agent credentials
+	for i := range l.Credentials {
+		if err := l.Credentials[i].init(l.ClientConfig); err != nil {
+			return err
+		}
+	}
+
 	// Preparing connection-builder function
 	l.getConnectionFunc = l.getConnection
+	l.failedOver = make(map[string]bool)
+
+	// Register the internal statistics
+	l.evictions = selfstat.Register("snmp_lookup", "cache_evictions", map[string]string{})
+	l.stats = make(map[string]*agentStats)
+
+	// Add the tag definitions from file
+	if l.TagsFile != "" {
+		defs, err := loadTagsFile(l.TagsFile)
+		if err != nil {
+			return fmt.Errorf("loading tags file: %w", err)
+		}
+		l.Tags = append(l.Tags, defs...)
+	}
 
 	// Initialize the table
 	l.table.Name = "lookup"
 	l.table.IndexAsTag = true
-	l.table.Fields = l.Tags
-	for i := range l.table.Fields {
-		l.table.Fields[i].IsTag = true
+	l.table.Fields = make([]snmp.Field, 0, len(l.Tags))
+	for i, def := range l.Tags {
+		if len(def.Destinations) == 0 {
+			def.Destinations = []string{"tag"}
+		}
+		for _, dest := range def.Destinations {
+			switch dest {
+			case "tag":
+				def.asTag = true
+			case "field":
+				def.asField = true
+			default:
+				return fmt.Errorf("invalid destination %q for tag %q", dest, def.Name)
+			}
+		}
+		l.Tags[i] = def
+
+		// Values also used as field must not be stringified by the table to
+		// preserve the converted type so we handle those as fields and create
+		// the tag when updating the agent.
+		f := def.Field
+		f.IsTag = !def.asField
+
+		// Resolve textual OIDs early to report the failing symbol
+		if err := f.Init(translator); err != nil {
+			return fmt.Errorf("translating OID %q failed: %w", f.Oid, err)
+		}
+		l.table.Fields = append(l.table.Fields, f)
+	}
+	if err := l.table.Init(translator); err != nil {
+		return err
+	}
+
+	// Use the names resolved during table initialization, e.g. for textual
+	// OIDs without an explicit name.
+	for i := range l.Tags {
+		l.Tags[i].Name = l.table.Fields[i].Name
 	}
 
-	return l.table.Init(translator)
+	// Only allow defaults for tags we would set on resolution
+	for key := range l.DefaultTags {
+		if !slices.ContainsFunc(l.Tags, func(def tagDefinition) bool { return def.asTag && def.Name == key }) {
+			return fmt.Errorf("default for %q does not match any configured tag", key)
+		}
+	}
+
+	return nil
 }
 
 func (l *Lookup) Start(acc telegraf.Accumulator) error {
 	l.backlog = newBacklog(acc, l.Log, l.Ordered)
+	l.backlog.failureTag = l.FailureTag
+	l.backlog.defaultTags = l.DefaultTags
 
-	l.cache = newStore(l.CacheSize, l.CacheTTL, l.ParallelLookups, l.MinTimeBetweenUpdates)
+	if l.MaxRequestsPerSecond > 0 {
+		l.limiter = limiter.NewRateLimiter(l.MaxRequestsPerSecond, time.Second)
+	}
+
+	l.cache = newStore(l.CacheSize, l.CacheTTL, l.CacheTTLJitter, l.ParallelLookups, l.MinTimeBetweenUpdates, l.MinRefreshInterval)
 	l.cache.update = l.updateAgent
 	l.cache.notify = l.backlog.resolve
+	l.cache.evicted = l.evicted
 
 	return nil
 }
@@ -93,6 +223,9 @@ func (l *Lookup) Stop() {
 	// Stop resolving
 	l.cache.destroy()
 	l.cache.purge()
+	if l.limiter != nil {
+		l.limiter.Stop()
+	}
 
 	// Adding unresolved metrics to avoid data loss
 	if n := l.backlog.destroy(); n > 0 {
@@ -102,43 +235,148 @@ func (l *Lookup) Stop() {
 
 func (l *Lookup) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
 	agent, found := m.GetTag(l.AgentTag)
+	if !found && l.DefaultAgent != "" {
+		agent, found = l.DefaultAgent, true
+	}
 	if !found {
 		l.Log.Warn("Agent tag missing")
+		l.markFailed(m, "agent_tag_missing")
 		acc.AddMetric(m)
 		return nil
 	}
 
-	index, found := m.GetTag(l.IndexTag)
+	index, found := l.getIndex(m)
 	if !found {
 		l.Log.Warn("Index tag missing")
+		l.markFailed(m, "index_tag_missing")
 		acc.AddMetric(m)
 		return nil
 	}
 
+	if l.IndexType == "ipv4" {
+		idx, err := ipv4Index(index)
+		if err != nil {
+			l.Log.Warnf("Cannot convert index %q: %v", index, err)
+			l.markFailed(m, "invalid_index")
+			acc.AddMetric(m)
+			return nil
+		}
+		index = idx
+	}
+
 	// Add the metric to the backlog before trying to resolve it
 	l.backlog.push(agent, index, m)
 
 	// Try to lookup the information from cache.
-	l.cache.lookup(agent, index)
+	if l.cache.lookup(agent, index) {
+		l.agentStats(agent).hits.Incr(1)
+	} else {
+		l.agentStats(agent).misses.Incr(1)
+	}
 
 	return nil
 }
 
+// getIndex returns the row index of the metric. If multiple index tags are
+// configured, the index is formed by joining the tag values in order using
+// "." as done for composite table indices.
+func (l *Lookup) getIndex(m telegraf.Metric) (string, bool) {
+	if len(l.IndexTags) == 0 {
+		return m.GetTag(l.IndexTag)
+	}
+
+	parts := make([]string, 0, len(l.IndexTags))
+	for _, key := range l.IndexTags {
+		v, found := m.GetTag(key)
+		if !found {
+			return "", false
+		}
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, "."), true
+}
+
+// agentStats returns the internal statistics of the given agent
+func (l *Lookup) agentStats(agent string) *agentStats {
+	l.Lock()
+	defer l.Unlock()
+
+	stats, found := l.stats[agent]
+	if !found {
+		tags := map[string]string{"agent": agent}
+		stats = &agentStats{
+			hits:       selfstat.Register("snmp_lookup", "cache_hits", tags),
+			misses:     selfstat.Register("snmp_lookup", "cache_misses", tags),
+			inflight:   selfstat.Register("snmp_lookup", "inflight_lookups", tags),
+			walkErrors: selfstat.Register("snmp_lookup", "walk_errors", tags),
+		}
+		l.stats[agent] = stats
+	}
+	return stats
+}
+
+func (l *Lookup) markFailed(m telegraf.Metric, reason string) {
+	if l.FailureTag != "" {
+		m.AddTag(l.FailureTag, reason)
+	}
+}
+
+func (l *Lookup) evicted(agent string) {
+	l.Log.Debugf("Evicted agent %q from cache due to size limit", agent)
+	l.evictions.Incr(1)
+}
+
 // Default update function
 func (l *Lookup) updateAgent(agent string) *tagMap {
+	inflight := l.agentStats(agent).inflight
+	inflight.Incr(1)
+	defer inflight.Incr(-1)
+
 	tm := &tagMap{created: time.Now()}
 
-	// Initialize connection to agent
-	conn, err := l.getConnectionFunc(agent)
-	if err != nil {
-		l.Log.Errorf("Getting connection for %q failed: %v", agent, err)
-		return tm
+	// Determine the agents to query and start with the currently live one
+	candidates := []string{agent}
+	secondary, hasSecondary := l.SecondaryAgents[agent]
+	if hasSecondary {
+		l.Lock()
+		failedOver := l.failedOver[agent]
+		l.Unlock()
+		if failedOver {
+			candidates = []string{secondary, agent}
+		} else {
+			candidates = []string{agent, secondary}
+		}
 	}
 
-	// Query table including translation
-	table, err := l.table.Build(conn, true)
-	if err != nil {
-		l.Log.Errorf("Building table for %q failed: %v", agent, err)
+	var table *snmp.RTable
+	for _, addr := range candidates {
+		var err error
+		table, err = l.queryAgent(addr)
+		if err != nil {
+			l.agentStats(addr).walkErrors.Incr(1)
+			l.Log.Error(err)
+			if l.EmitErrors {
+				l.backlog.emit(metric.New(
+					"snmp_lookup_errors",
+					map[string]string{"agent": addr},
+					map[string]interface{}{"error": err.Error()},
+					time.Now(),
+				))
+			}
+			continue
+		}
+		if hasSecondary {
+			l.Lock()
+			if l.failedOver[agent] != (addr == secondary) {
+				l.Log.Infof("Using agent %q for %q", addr, agent)
+			}
+			l.failedOver[agent] = addr == secondary
+			l.Unlock()
+		}
+		break
+	}
+	if table == nil {
+		tm.failed = true
 		return tm
 	}
 
@@ -148,14 +386,116 @@ func (l *Lookup) updateAgent(agent string) *tagMap {
 	for _, row := range table.Rows {
 		index := row.Tags["index"]
 		delete(row.Tags, "index")
+		if l.IndexOffset > 0 {
+			parts := strings.SplitN(index, ".", l.IndexOffset+1)
+			if len(parts) <= l.IndexOffset {
+				l.Log.Debugf("Skipping row with index %q shorter than the offset", index)
+				continue
+			}
+			index = parts[l.IndexOffset]
+		}
+		if l.IndexType == "ipv4" {
+			idx, err := ipv4Index(index)
+			if err != nil {
+				l.Log.Debugf("Skipping row with index %q: %v", index, err)
+				continue
+			}
+			index = idx
+		}
 		tm.rows[index] = row.Tags
+
+		// Distribute the values used as fields to their destinations
+		if len(row.Fields) == 0 {
+			continue
+		}
+		for _, def := range l.Tags {
+			v, found := row.Fields[def.Name]
+			if !found {
+				continue
+			}
+			if def.asTag {
+				tm.rows[index][def.Name] = fmt.Sprintf("%v", v)
+			}
+			if !def.asField {
+				delete(row.Fields, def.Name)
+			}
+		}
+		if len(row.Fields) > 0 {
+			if tm.fields == nil {
+				tm.fields = make(fieldMapRows)
+			}
+			tm.fields[index] = row.Fields
+		}
 	}
 
 	return tm
 }
 
+// ipv4Index converts the given index to the dotted IPv4 form. Both the
+// dotted IP and the OID sub-identifier form are accepted, the latter may
+// contain prefixes like the address type and length as used with
+// InetAddress indices so only the last four sub-identifiers are used.
+func ipv4Index(index string) (string, error) {
+	parts := strings.Split(index, ".")
+	if len(parts) < 4 {
+		return "", errors.New("not enough sub-identifiers for IPv4 address")
+	}
+	parts = parts[len(parts)-4:]
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 8); err != nil {
+			return "", fmt.Errorf("invalid octet %q", p)
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func (l *Lookup) queryAgent(agent string) (*snmp.RTable, error) {
+	// Initialize connection to agent
+	conn, err := l.getConnectionFunc(agent)
+	if err != nil {
+		return nil, fmt.Errorf("getting connection for %q failed: %w", agent, err)
+	}
+	if l.limiter != nil {
+		conn = &limitedConnection{Connection: conn, limiter: l.limiter}
+	}
+
+	// Query table including translation
+	table, err := l.table.Build(conn, true)
+	if err != nil {
+		return nil, fmt.Errorf("building table for %q failed: %w", agent, err)
+	}
+
+	return table, nil
+}
+
+// limitedConnection delays walks and gets to limit the SNMP requests across
+// all agents to the configured rate.
+type limitedConnection struct {
+	snmp.Connection
+	limiter *limiter.RateLimiter
+}
+
+func (c *limitedConnection) Walk(oid string, fn gosnmp.WalkFunc) error {
+	<-c.limiter.C
+	return c.Connection.Walk(oid, fn)
+}
+
+func (c *limitedConnection) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	<-c.limiter.C
+	return c.Connection.Get(oids)
+}
+
 func (l *Lookup) getConnection(agent string) (snmp.Connection, error) {
-	conn, err := snmp.NewWrapper(l.ClientConfig)
+	// Use the first matching credentials for the agent
+	cfg := l.ClientConfig
+	for i := range l.Credentials {
+		if l.Credentials[i].filter.Match(agent) {
+			cfg = l.Credentials[i].apply(cfg)
+			break
+		}
+	}
+
+	conn, err := snmp.NewWrapper(cfg)
 	if err != nil {
 		return conn, fmt.Errorf("parsing SNMP client config: %w", err)
 	}
@@ -11,6 +11,7 @@ import (
 	"github.com/influxdata/telegraf/internal/snmp"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/gosnmp/gosnmp"
@@ -96,10 +97,12 @@ func TestInit(t *testing.T) {
 		{
 			name: "table init",
 			plugin: &Lookup{
-				Tags: []snmp.Field{
+				Tags: []tagDefinition{
 					{
-						Name: "ifName",
-						Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+						Field: snmp.Field{
+							Name: "ifName",
+							Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+						},
 					},
 				},
 			},
@@ -119,6 +122,115 @@ func TestInit(t *testing.T) {
 	}
 }
 
+func TestInitTranslation(t *testing.T) {
+	cfg := *snmp.DefaultClientConfig()
+	cfg.Path = []string{"../../../internal/snmp/testdata/gosmi"}
+
+	plugin := &Lookup{
+		ClientConfig: cfg,
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Oid: "IF-MIB::ifPhysAddress",
+				},
+			},
+		},
+		Log: testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "ifPhysAddress", plugin.Tags[0].Name)
+	require.Equal(t, ".1.3.6.1.2.1.2.2.1.6", plugin.table.Fields[0].Oid)
+
+	plugin = &Lookup{
+		ClientConfig: cfg,
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Oid: "IF-MIB::ifUnknown",
+				},
+			},
+		},
+		Log: testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.ErrorContains(t, plugin.Init(), `translating OID "IF-MIB::ifUnknown" failed`)
+}
+
+func TestTagsFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		expected []tagDefinition
+		err      string
+	}{
+		{
+			name:     "csv",
+			filename: "testdata/tags.csv",
+			expected: []tagDefinition{
+				{
+					Field:        snmp.Field{Name: "ifName", Oid: ".1.3.6.1.2.1.31.1.1.1.1"},
+					Destinations: []string{"tag"},
+					asTag:        true,
+				},
+				{
+					Field:        snmp.Field{Name: "ifHighSpeed", Oid: ".1.3.6.1.2.1.31.1.1.1.15", Conversion: "int"},
+					Destinations: []string{"tag"},
+					asTag:        true,
+				},
+			},
+		},
+		{
+			name:     "json",
+			filename: "testdata/tags.json",
+			expected: []tagDefinition{
+				{
+					Field:        snmp.Field{Name: "ifName", Oid: ".1.3.6.1.2.1.31.1.1.1.1"},
+					Destinations: []string{"tag"},
+					asTag:        true,
+				},
+				{
+					Field:        snmp.Field{Name: "ifHighSpeed", Oid: ".1.3.6.1.2.1.31.1.1.1.15", Conversion: "int"},
+					Destinations: []string{"field"},
+					asField:      true,
+				},
+			},
+		},
+		{
+			name:     "invalid csv",
+			filename: "testdata/tags_invalid.csv",
+			err:      "line 1 in \"testdata/tags_invalid.csv\" has 1 columns",
+		},
+		{
+			name:     "non-existing",
+			filename: "testdata/nonexisting.json",
+			err:      "loading tags file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Lookup{
+				TagsFile: tt.filename,
+				Log:      testutil.Logger{Name: "processors.snmp_lookup"},
+			}
+			if tt.err != "" {
+				require.ErrorContains(t, plugin.Init(), tt.err)
+				return
+			}
+			require.NoError(t, plugin.Init())
+			require.Len(t, plugin.Tags, len(tt.expected))
+			for i, expected := range tt.expected {
+				actual := plugin.Tags[i]
+				require.Equal(t, expected.Name, actual.Name)
+				require.Equal(t, expected.Oid, actual.Oid)
+				require.Equal(t, expected.Conversion, actual.Conversion)
+				require.Equal(t, expected.Destinations, actual.Destinations)
+				require.Equal(t, expected.asTag, actual.asTag)
+				require.Equal(t, expected.asField, actual.asField)
+			}
+		})
+	}
+}
+
 func TestStart(t *testing.T) {
 	plugin := Lookup{}
 	require.NoError(t, plugin.Init())
@@ -184,16 +296,69 @@ func TestGetConnection(t *testing.T) {
 	}
 }
 
+func TestGetConnectionCredentials(t *testing.T) {
+	p := Lookup{
+		AgentTag:     "source",
+		ClientConfig: *snmp.DefaultClientConfig(),
+		Credentials: []credentials{
+			{
+				Agents:       []string{"10.0.1.*"},
+				Version:      3,
+				SecName:      "secure",
+				SecLevel:     "authPriv",
+				AuthProtocol: "SHA",
+				AuthPassword: config.NewSecret([]byte("authpass")),
+				PrivProtocol: "AES",
+				PrivPassword: config.NewSecret([]byte("privpass")),
+			},
+		},
+		Log: testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, p.Init())
+
+	// Matching agents should use the credentials
+	conn, err := p.getConnection("10.0.1.5")
+	require.NoError(t, err)
+	gs := conn.(snmp.GosnmpWrapper)
+	require.Equal(t, gosnmp.Version3, gs.Version)
+	sp := gs.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.Equal(t, "secure", sp.UserName)
+	require.Equal(t, "authpass", sp.AuthenticationPassphrase)
+	require.Equal(t, "privpass", sp.PrivacyPassphrase)
+
+	// All other agents should use the default settings
+	conn, err = p.getConnection("10.0.2.5")
+	require.NoError(t, err)
+	require.Equal(t, gosnmp.Version2c, conn.(snmp.GosnmpWrapper).Version)
+}
+
+func TestInitInvalidCredentials(t *testing.T) {
+	p := Lookup{
+		ClientConfig: *snmp.DefaultClientConfig(),
+		Credentials: []credentials{
+			{
+				Agents:   []string{"10.0.1.*"},
+				Version:  3,
+				SecLevel: "foo",
+			},
+		},
+		Log: testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.ErrorContains(t, p.Init(), "invalid secLevel")
+}
+
 func TestUpdateAgent(t *testing.T) {
 	p := Lookup{
 		ClientConfig: *snmp.DefaultClientConfig(),
 		CacheSize:    defaultCacheSize,
 		CacheTTL:     defaultCacheTTL,
 		Log:          testutil.Logger{Name: "processors.snmp_lookup"},
-		Tags: []snmp.Field{
+		Tags: []tagDefinition{
 			{
-				Name: "ifName",
-				Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
 			},
 		},
 	}
@@ -254,6 +419,366 @@ func TestUpdateAgent(t *testing.T) {
 	})
 }
 
+func TestUpdateAgentRateLimit(t *testing.T) {
+	plugin := &Lookup{
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+		MaxRequestsPerSecond: 1,
+		ClientConfig:         *snmp.DefaultClientConfig(),
+		Log:                  testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	tsc := &testSNMPConnection{
+		values: map[string]string{
+			".1.3.6.1.2.1.31.1.1.1.1.0": "eth0",
+		},
+	}
+	plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+		return tsc, nil
+	}
+
+	// The second walk must wait for the next period
+	start := time.Now()
+	plugin.updateAgent("127.0.0.1")
+	plugin.updateAgent("127.0.0.2")
+	require.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	require.EqualValues(t, 2, tsc.calls.Load())
+}
+
+func TestUpdateAgentSecondary(t *testing.T) {
+	p := Lookup{
+		ClientConfig:    *snmp.DefaultClientConfig(),
+		SecondaryAgents: map[string]string{"10.0.0.1": "10.0.0.2"},
+		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	tsc := &testSNMPConnection{
+		values: map[string]string{
+			".1.3.6.1.2.1.31.1.1.1.1.0": "eth0",
+		},
+	}
+	primaryUp := false
+	var requested []string
+	p.getConnectionFunc = func(agent string) (snmp.Connection, error) {
+		requested = append(requested, agent)
+		if agent == "10.0.0.1" && !primaryUp {
+			return nil, errors.New("unreachable")
+		}
+		return tsc, nil
+	}
+	expected := tagMapRows{"0": {"ifName": "eth0"}}
+
+	// Fail over to the secondary agent
+	require.Equal(t, expected, p.updateAgent("10.0.0.1").rows)
+	require.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, requested)
+
+	// The secondary agent should be tried first as long as it is live
+	requested = nil
+	primaryUp = true
+	require.Equal(t, expected, p.updateAgent("10.0.0.1").rows)
+	require.Equal(t, []string{"10.0.0.2"}, requested)
+
+	// Unreachable secondary should switch back to the primary
+	requested = nil
+	p.getConnectionFunc = func(agent string) (snmp.Connection, error) {
+		requested = append(requested, agent)
+		if agent == "10.0.0.2" {
+			return nil, errors.New("unreachable")
+		}
+		return tsc, nil
+	}
+	require.Equal(t, expected, p.updateAgent("10.0.0.1").rows)
+	require.Equal(t, []string{"10.0.0.2", "10.0.0.1"}, requested)
+
+	requested = nil
+	require.Equal(t, expected, p.updateAgent("10.0.0.1").rows)
+	require.Equal(t, []string{"10.0.0.1"}, requested)
+}
+
+func TestUpdateAgentEmitErrors(t *testing.T) {
+	plugin := &Lookup{
+		EmitErrors: true,
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+		ClientConfig: *snmp.DefaultClientConfig(),
+		Log:          testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+		return nil, errors.New("unreachable")
+	}
+
+	tm := plugin.updateAgent("127.0.0.1")
+	require.Empty(t, tm.rows)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"snmp_lookup_errors",
+			map[string]string{"agent": "127.0.0.1"},
+			map[string]interface{}{"error": `getting connection for "127.0.0.1" failed: unreachable`},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestUpdateAgentDestinations(t *testing.T) {
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadConfigData([]byte(`
+[[processors.snmp_lookup]]
+  [[processors.snmp_lookup.tag]]
+    oid = ".1.3.6.1.2.1.31.1.1.1.1"
+    name = "ifName"
+
+  [[processors.snmp_lookup.tag]]
+    oid = ".1.3.6.1.2.1.31.1.1.1.15"
+    name = "ifHighSpeed"
+    conversion = "int"
+    destinations = ["tag", "field"]
+
+  [[processors.snmp_lookup.tag]]
+    oid = ".1.3.6.1.2.1.31.1.1.1.6"
+    name = "ifHCInOctets"
+    conversion = "int"
+    destinations = ["field"]
+`)))
+	require.Len(t, cfg.Processors, 1)
+	p := cfg.Processors[0].Processor.(*Lookup)
+	p.Log = testutil.Logger{Name: "processors.snmp_lookup"}
+	require.NoError(t, p.Init())
+
+	p.getConnectionFunc = func(string) (snmp.Connection, error) {
+		return &testSNMPConnection{
+			values: map[string]string{
+				".1.3.6.1.2.1.31.1.1.1.1.0":  "eth0",
+				".1.3.6.1.2.1.31.1.1.1.15.0": "1000",
+				".1.3.6.1.2.1.31.1.1.1.6.0":  "42",
+			},
+		}, nil
+	}
+
+	tm := p.updateAgent("127.0.0.1")
+	require.Equal(t, tagMapRows{
+		"0": {"ifName": "eth0", "ifHighSpeed": "1000"},
+	}, tm.rows)
+	require.Equal(t, fieldMapRows{
+		"0": {"ifHighSpeed": int64(1000), "ifHCInOctets": int64(42)},
+	}, tm.fields)
+}
+
+func TestUpdateAgentIPv4Index(t *testing.T) {
+	plugin := &Lookup{
+		IndexType: "ipv4",
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifIndex",
+					Oid:  ".1.3.6.1.2.1.4.34.1.3",
+				},
+			},
+		},
+		ClientConfig: *snmp.DefaultClientConfig(),
+		Log:          testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+		return &testSNMPConnection{
+			values: map[string]string{
+				".1.3.6.1.2.1.4.34.1.3.1.4.10.0.0.1":    "1",
+				".1.3.6.1.2.1.4.34.1.3.1.4.192.168.1.1": "2",
+			},
+		}, nil
+	}
+
+	tm := plugin.updateAgent("127.0.0.1")
+	require.Equal(t, tagMapRows{
+		"10.0.0.1":    {"ifIndex": "1"},
+		"192.168.1.1": {"ifIndex": "2"},
+	}, tm.rows)
+}
+
+func TestUpdateAgentIndexOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		offset   int
+		expected tagMapRows
+	}{
+		{
+			name:   "no offset",
+			offset: 0,
+			expected: tagMapRows{
+				"6.1.2":  {"ifName": "eth0"},
+				"6.1.10": {"ifName": "eth1"},
+				"24.3":   {"ifName": "lo"},
+			},
+		},
+		{
+			name:   "offset one",
+			offset: 1,
+			expected: tagMapRows{
+				"1.2":  {"ifName": "eth0"},
+				"1.10": {"ifName": "eth1"},
+				"3":    {"ifName": "lo"},
+			},
+		},
+		{
+			name:   "offset two",
+			offset: 2,
+			expected: tagMapRows{
+				"2":  {"ifName": "eth0"},
+				"10": {"ifName": "eth1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Lookup{
+				IndexOffset: tt.offset,
+				Tags: []tagDefinition{
+					{
+						Field: snmp.Field{
+							Name: "ifName",
+							Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+						},
+					},
+				},
+				ClientConfig: *snmp.DefaultClientConfig(),
+				Log:          testutil.Logger{Name: "processors.snmp_lookup"},
+			}
+			require.NoError(t, plugin.Init())
+
+			plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+				return &testSNMPConnection{
+					values: map[string]string{
+						".1.3.6.1.2.1.31.1.1.1.1.6.1.2":  "eth0",
+						".1.3.6.1.2.1.31.1.1.1.1.6.1.10": "eth1",
+						".1.3.6.1.2.1.31.1.1.1.1.24.3":   "lo",
+					},
+				}, nil
+			}
+
+			tm := plugin.updateAgent("127.0.0.1")
+			require.Equal(t, tt.expected, tm.rows)
+		})
+	}
+}
+
+func TestIPv4Index(t *testing.T) {
+	tests := []struct {
+		name     string
+		index    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "dotted",
+			index:    "192.168.1.1",
+			expected: "192.168.1.1",
+		},
+		{
+			name:     "with type and length",
+			index:    "1.4.192.168.1.1",
+			expected: "192.168.1.1",
+		},
+		{
+			name:  "too short",
+			index: "192.168.1",
+			err:   "not enough sub-identifiers",
+		},
+		{
+			name:  "invalid octet",
+			index: "192.168.1.256",
+			err:   `invalid octet "256"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ipv4Index(tt.index)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestInitInvalidIndexType(t *testing.T) {
+	plugin := &Lookup{
+		IndexType: "ipv6",
+		Log:       testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.ErrorContains(t, plugin.Init(), `invalid index type "ipv6"`)
+}
+
+func TestInitOidEnd(t *testing.T) {
+	cfg := config.NewConfig()
+	require.NoError(t, cfg.LoadConfigData([]byte(`
+[[processors.snmp_lookup]]
+  [[processors.snmp_lookup.tag]]
+    oid = ".1.3.6.1.2.1.31.1.1.1.1"
+    oid_end = ".1.3.6.1.2.1.31.1.1.1.1.100"
+    name = "ifName"
+`)))
+	require.Len(t, cfg.Processors, 1)
+	p := cfg.Processors[0].Processor.(*Lookup)
+	p.Log = testutil.Logger{Name: "processors.snmp_lookup"}
+	require.NoError(t, p.Init())
+	require.Len(t, p.table.Fields, 1)
+	require.Equal(t, ".1.3.6.1.2.1.31.1.1.1.1.100", p.table.Fields[0].OidEnd)
+}
+
+func TestInitInvalidDestination(t *testing.T) {
+	plugin := &Lookup{
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+				Destinations: []string{"metric"},
+			},
+		},
+		Log: testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.ErrorContains(t, plugin.Init(), `invalid destination "metric" for tag "ifName"`)
+}
+
 func TestAdd(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -375,6 +900,388 @@ func TestAdd(t *testing.T) {
 	require.EqualValues(t, 0, tsc.calls.Load())
 }
 
+func TestAddIndexTags(t *testing.T) {
+	plugin := Lookup{
+		AgentTag:        "source",
+		IndexTag:        "index",
+		IndexTags:       []string{"vlan", "mac"},
+		ClientConfig:    *snmp.DefaultClientConfig(),
+		CacheSize:       defaultCacheSize,
+		CacheTTL:        defaultCacheTTL,
+		ParallelLookups: defaultParallelLookups,
+		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Sneak in cached data
+	plugin.cache.cache.Add("127.0.0.1", &tagMap{rows: map[string]map[string]string{"10.0.17.42.1.2.3": {"port": "7"}}})
+
+	input := testutil.MustMetric(
+		"test",
+		map[string]string{"source": "127.0.0.1", "index": "1", "vlan": "10", "mac": "0.17.42.1.2.3"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{"source": "127.0.0.1", "index": "1", "vlan": "10", "mac": "0.17.42.1.2.3", "port": "7"},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	}
+
+	require.NoError(t, plugin.Add(input, &acc))
+	require.Eventually(t, func() bool {
+		return int(acc.NMetrics()) >= len(expected)
+	}, 3*time.Second, 100*time.Millisecond)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestAddDefaultTags(t *testing.T) {
+	plugin := Lookup{
+		AgentTag: "source",
+		IndexTag: "index",
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+		DefaultTags:     map[string]string{"ifName": "unknown"},
+		ClientConfig:    *snmp.DefaultClientConfig(),
+		CacheSize:       defaultCacheSize,
+		CacheTTL:        defaultCacheTTL,
+		ParallelLookups: defaultParallelLookups,
+		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Sneak in cached data
+	plugin.cache.cache.Add("127.0.0.1", &tagMap{rows: map[string]map[string]string{"123": {"ifName": "eth123"}}})
+
+	input := testutil.MustMetric(
+		"test",
+		map[string]string{"source": "127.0.0.1", "index": "999"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{"source": "127.0.0.1", "index": "999", "ifName": "unknown"},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	}
+
+	require.NoError(t, plugin.Add(input, &acc))
+	require.Eventually(t, func() bool {
+		return int(acc.NMetrics()) >= len(expected)
+	}, 3*time.Second, 100*time.Millisecond)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestInitInvalidDefaultTags(t *testing.T) {
+	plugin := &Lookup{
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+		DefaultTags: map[string]string{"ifAlias": "unknown"},
+		Log:         testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.ErrorContains(t, plugin.Init(), `default for "ifAlias" does not match any configured tag`)
+}
+
+func TestCacheStats(t *testing.T) {
+	plugin := Lookup{
+		AgentTag: "source",
+		IndexTag: "index",
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+		ClientConfig:    *snmp.DefaultClientConfig(),
+		CacheSize:       defaultCacheSize,
+		CacheTTL:        defaultCacheTTL,
+		ParallelLookups: defaultParallelLookups,
+		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+		return &testSNMPConnection{}, nil
+	}
+
+	// Sneak in cached data
+	plugin.cache.cache.Add("127.0.0.1", &tagMap{rows: map[string]map[string]string{"123": {"ifName": "eth123"}}})
+
+	// Stats are global so only check the difference
+	hits := selfstat.Register("snmp_lookup", "cache_hits", map[string]string{"agent": "127.0.0.1"})
+	misses := selfstat.Register("snmp_lookup", "cache_misses", map[string]string{"agent": "127.0.0.1"})
+	missesFailing := selfstat.Register("snmp_lookup", "cache_misses", map[string]string{"agent": "127.0.0.2"})
+	inflight := selfstat.Register("snmp_lookup", "inflight_lookups", map[string]string{"agent": "127.0.0.2"})
+	hitsBefore, missesBefore, missesFailingBefore := hits.Get(), misses.Get(), missesFailing.Get()
+	walkErrors := selfstat.Register("snmp_lookup", "walk_errors", map[string]string{"agent": "127.0.0.2"})
+	walkErrorsBefore := walkErrors.Get()
+
+	inputs := []telegraf.Metric{
+		testutil.MustMetric("test", map[string]string{"source": "127.0.0.1", "index": "123"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("test", map[string]string{"source": "127.0.0.1", "index": "999"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		testutil.MustMetric("test", map[string]string{"source": "127.0.0.2", "index": "123"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+	}
+	for _, m := range inputs {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	require.Eventually(t, func() bool {
+		return int(acc.NMetrics()) >= len(inputs)
+	}, 3*time.Second, 100*time.Millisecond)
+
+	require.Equal(t, int64(1), hits.Get()-hitsBefore)
+	require.Equal(t, int64(1), misses.Get()-missesBefore)
+	require.Equal(t, int64(1), missesFailing.Get()-missesFailingBefore)
+	require.Equal(t, int64(1), walkErrors.Get()-walkErrorsBefore)
+	require.Zero(t, inflight.Get())
+}
+
+func TestWalkErrorsConnection(t *testing.T) {
+	plugin := Lookup{
+		AgentTag: "source",
+		IndexTag: "index",
+		Tags: []tagDefinition{
+			{
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
+			},
+		},
+		ClientConfig:    *snmp.DefaultClientConfig(),
+		CacheSize:       defaultCacheSize,
+		CacheTTL:        defaultCacheTTL,
+		ParallelLookups: defaultParallelLookups,
+		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	// Stats are global so only check the difference
+	walkErrors := selfstat.Register("snmp_lookup", "walk_errors", map[string]string{"agent": "10.0.0.3"})
+	walkErrorsBefore := walkErrors.Get()
+
+	m := testutil.MustMetric("test", map[string]string{"source": "10.0.0.3", "index": "1"}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, plugin.Add(m, &acc))
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() >= 1
+	}, 3*time.Second, 100*time.Millisecond)
+
+	require.Equal(t, int64(1), walkErrors.Get()-walkErrorsBefore)
+}
+
+func TestCacheStatsPerAgent(t *testing.T) {
+	newPlugin := func() *Lookup {
+		plugin := &Lookup{
+			AgentTag: "source",
+			IndexTag: "index",
+			Tags: []tagDefinition{
+				{
+					Field: snmp.Field{
+						Name: "ifName",
+						Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+					},
+				},
+			},
+			ClientConfig:    *snmp.DefaultClientConfig(),
+			CacheSize:       defaultCacheSize,
+			CacheTTL:        defaultCacheTTL,
+			ParallelLookups: defaultParallelLookups,
+			Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+		}
+		require.NoError(t, plugin.Init())
+		return plugin
+	}
+
+	// Use two instances serving different agents
+	var acc testutil.Accumulator
+	first := newPlugin()
+	require.NoError(t, first.Start(&acc))
+	defer first.Stop()
+	first.cache.cache.Add("10.0.0.1", &tagMap{rows: map[string]map[string]string{"1": {"ifName": "eth1"}}})
+
+	second := newPlugin()
+	require.NoError(t, second.Start(&acc))
+	defer second.Stop()
+	second.cache.cache.Add("10.0.0.2", &tagMap{rows: map[string]map[string]string{"1": {"ifName": "eth1"}}})
+
+	hitsFirst := selfstat.Register("snmp_lookup", "cache_hits", map[string]string{"agent": "10.0.0.1"})
+	hitsSecond := selfstat.Register("snmp_lookup", "cache_hits", map[string]string{"agent": "10.0.0.2"})
+	hitsFirstBefore, hitsSecondBefore := hitsFirst.Get(), hitsSecond.Get()
+
+	m := testutil.MustMetric("test", map[string]string{"source": "10.0.0.1", "index": "1"}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, first.Add(m, &acc))
+	require.NoError(t, first.Add(m.Copy(), &acc))
+	m = testutil.MustMetric("test", map[string]string{"source": "10.0.0.2", "index": "1"}, map[string]interface{}{"value": 2}, time.Unix(0, 0))
+	require.NoError(t, second.Add(m, &acc))
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() >= 3
+	}, 3*time.Second, 100*time.Millisecond)
+
+	require.Equal(t, int64(2), hitsFirst.Get()-hitsFirstBefore)
+	require.Equal(t, int64(1), hitsSecond.Get()-hitsSecondBefore)
+}
+
+func TestAddFailureTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    telegraf.Metric
+		expected string
+	}{
+		{
+			name: "no index tag",
+			input: testutil.MustMetric(
+				"test",
+				map[string]string{"source": "127.0.0.1"},
+				map[string]interface{}{"value": 42},
+				time.Unix(0, 0),
+			),
+			expected: "index_tag_missing",
+		},
+		{
+			name: "index not found",
+			input: testutil.MustMetric(
+				"test",
+				map[string]string{"source": "127.0.0.1", "index": "999"},
+				map[string]interface{}{"value": 42},
+				time.Unix(0, 0),
+			),
+			expected: "index_not_found",
+		},
+		{
+			name: "agent error",
+			input: testutil.MustMetric(
+				"test",
+				map[string]string{"source": "127.0.0.2", "index": "123"},
+				map[string]interface{}{"value": 42},
+				time.Unix(0, 0),
+			),
+			expected: "agent_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := Lookup{
+				AgentTag:        "source",
+				IndexTag:        "index",
+				FailureTag:      "snmp_lookup_failed",
+				ClientConfig:    *snmp.DefaultClientConfig(),
+				CacheSize:       defaultCacheSize,
+				CacheTTL:        defaultCacheTTL,
+				ParallelLookups: defaultParallelLookups,
+				Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Start(&acc))
+			defer plugin.Stop()
+
+			plugin.getConnectionFunc = func(string) (snmp.Connection, error) {
+				return nil, errors.New("unreachable")
+			}
+
+			// Sneak in cached data
+			plugin.cache.cache.Add("127.0.0.1", &tagMap{rows: map[string]map[string]string{"123": {"ifName": "eth123"}}})
+
+			require.NoError(t, plugin.Add(tt.input, &acc))
+			require.Eventually(t, func() bool {
+				return acc.NMetrics() >= 1
+			}, 3*time.Second, 100*time.Millisecond)
+
+			metrics := acc.GetTelegrafMetrics()
+			require.Len(t, metrics, 1)
+			reason, found := metrics[0].GetTag("snmp_lookup_failed")
+			require.True(t, found)
+			require.Equal(t, tt.expected, reason)
+		})
+	}
+}
+
+func TestAddDefaultAgent(t *testing.T) {
+	plugin := Lookup{
+		AgentTag:        "source",
+		DefaultAgent:    "127.0.0.1",
+		IndexTag:        "index",
+		ClientConfig:    *snmp.DefaultClientConfig(),
+		CacheSize:       defaultCacheSize,
+		CacheTTL:        defaultCacheTTL,
+		ParallelLookups: defaultParallelLookups,
+		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Sneak in cached data
+	plugin.cache.cache.Add("127.0.0.1", &tagMap{rows: map[string]map[string]string{"123": {"ifName": "eth123"}}})
+
+	input := testutil.MustMetric(
+		"test",
+		map[string]string{"index": "123"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{
+				"index":  "123",
+				"ifName": "eth123",
+			},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	}
+
+	require.NoError(t, plugin.Add(input, &acc))
+	require.Eventually(t, func() bool {
+		return int(acc.NMetrics()) >= len(expected)
+	}, 3*time.Second, 100*time.Millisecond)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
 func TestExpiry(t *testing.T) {
 	p := Lookup{
 		AgentTag:        "source",
@@ -383,10 +1290,12 @@ func TestExpiry(t *testing.T) {
 		CacheTTL:        defaultCacheTTL,
 		ParallelLookups: defaultParallelLookups,
 		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
-		Tags: []snmp.Field{
+		Tags: []tagDefinition{
 			{
-				Name: "ifName",
-				Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
 			},
 		},
 	}
@@ -496,10 +1405,12 @@ func TestOrdered(t *testing.T) {
 		ParallelLookups: defaultParallelLookups,
 		Ordered:         true,
 		Log:             testutil.Logger{Name: "processors.snmp_lookup"},
-		Tags: []snmp.Field{
+		Tags: []tagDefinition{
 			{
-				Name: "ifName",
-				Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				Field: snmp.Field{
+					Name: "ifName",
+					Oid:  ".1.3.6.1.2.1.31.1.1.1.1",
+				},
 			},
 		},
 	}
@@ -2,7 +2,9 @@ package snmp_lookup
 
 import (
 	"errors"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alitto/pond"
@@ -15,28 +17,73 @@ var ErrNotYetAvailable = errors.New("data not yet available")
 type store struct {
 	cache                *expirable.LRU[string, *tagMap]
 	pool                 *pond.WorkerPool
+	ttl                  time.Duration
+	ttlJitter            time.Duration
+	manageTTL            bool
 	minUpdateInterval    time.Duration
+	minRefreshInterval   time.Duration
+	lastRefresh          map[string]time.Time
+	purging              atomic.Bool
 	inflight             sync.Map
 	deferredUpdates      map[string]time.Time
 	deferredUpdatesTimer *time.Timer
 	notify               func(string, *tagMap)
 	update               func(string) *tagMap
+	evicted              func(string)
 
 	sync.Mutex
 }
 
-func newStore(size int, ttl config.Duration, workers int, minUpdateInterval config.Duration) *store {
-	return &store{
-		cache:             expirable.NewLRU[string, *tagMap](size, nil, time.Duration(ttl)),
-		pool:              pond.New(workers, 0, pond.MinWorkers(workers/2+1)),
-		deferredUpdates:   make(map[string]time.Time),
-		minUpdateInterval: time.Duration(minUpdateInterval),
+func newStore(size int, ttl, ttlJitter config.Duration, workers int, minUpdateInterval, minRefreshInterval config.Duration) *store {
+	s := &store{
+		pool:               pond.New(workers, 0, pond.MinWorkers(workers/2+1)),
+		deferredUpdates:    make(map[string]time.Time),
+		lastRefresh:        make(map[string]time.Time),
+		ttl:                time.Duration(ttl),
+		ttlJitter:          time.Duration(ttlJitter),
+		minUpdateInterval:  time.Duration(minUpdateInterval),
+		minRefreshInterval: time.Duration(minRefreshInterval),
 	}
+
+	// With a minimum refresh interval we keep expired entries to serve them
+	// while refreshing in the background and with jitter the TTL differs per
+	// entry, so we handle the TTL ourselves in those cases.
+	cacheTTL := time.Duration(ttl)
+	if s.minRefreshInterval > 0 || s.ttlJitter > 0 {
+		s.manageTTL = true
+		cacheTTL = 0
+	}
+	s.cache = expirable.NewLRU[string, *tagMap](size, s.onEvict, cacheTTL)
+
+	return s
+}
+
+func (s *store) onEvict(agent string, entry *tagMap) {
+	// The LRU calls this function for all removed entries, so ignore the
+	// entries removed due to purging the cache or expiry of the TTL, by the
+	// LRU or by ourselves, as we only want to report agents removed due to the
+	// cache size limit.
+	if s.evicted == nil || s.purging.Load() {
+		return
+	}
+	if entry != nil && s.expired(entry) {
+		return
+	}
+	s.evicted(agent)
 }
 
 func (s *store) addBacklog(agent string, earliest time.Time) {
 	s.Lock()
 	defer s.Unlock()
+
+	// Never schedule an update within the minimum refresh interval as the
+	// timer bypasses the check in refresh().
+	if last, found := s.lastRefresh[agent]; found && s.minRefreshInterval > 0 {
+		if next := last.Add(s.minRefreshInterval); next.After(earliest) {
+			earliest = next
+		}
+	}
+
 	t, found := s.deferredUpdates[agent]
 	if !found || t.After(earliest) {
 		s.deferredUpdates[agent] = earliest
@@ -69,12 +116,33 @@ func (s *store) refreshTimer() {
 	s.deferredUpdatesTimer = time.AfterFunc(time.Until(earliest), func() { s.enqueue(agent) })
 }
 
+// refresh enqueues an update of the agent unless the agent was updated within
+// the minimum refresh interval. In this case the update is deferred.
+func (s *store) refresh(agent string) {
+	if s.minRefreshInterval > 0 {
+		s.Lock()
+		last, found := s.lastRefresh[agent]
+		s.Unlock()
+		if found && time.Since(last) < s.minRefreshInterval {
+			s.addBacklog(agent, last.Add(s.minRefreshInterval))
+			return
+		}
+	}
+	s.enqueue(agent)
+}
+
 func (s *store) enqueue(agent string) {
 	if _, inflight := s.inflight.LoadOrStore(agent, true); inflight {
 		return
 	}
 	s.pool.Submit(func() {
+		if s.minRefreshInterval > 0 {
+			s.recordRefresh(agent)
+		}
 		entry := s.update(agent)
+		if s.ttlJitter > 0 {
+			entry.ttl = s.jitteredTTL(agent)
+		}
 		s.cache.Add(agent, entry)
 		s.removeBacklog(agent)
 		s.notify(agent, entry)
@@ -82,26 +150,40 @@ func (s *store) enqueue(agent string) {
 	})
 }
 
-func (s *store) lookup(agent string, index string) {
+// lookup resolves the index for the agent and returns true if the index was
+// found in the cache.
+func (s *store) lookup(agent string, index string) bool {
 	entry, cached := s.cache.Get(agent)
 	if !cached {
 		// There is no cache at all, so we need to enqueue an update.
-		s.enqueue(agent)
-		return
+		s.refresh(agent)
+		return false
+	}
+
+	if s.manageTTL && s.expired(entry) {
+		// Refresh expired entries in the background but continue to serve the
+		// stale data in the meantime if requested.
+		if s.minRefreshInterval == 0 {
+			s.cache.Remove(agent)
+			s.refresh(agent)
+			return false
+		}
+		s.refresh(agent)
 	}
 
 	// In case the index does not exist, we need to update the agent as this
 	// new index might have been added in the meantime (e.g. after hot-plugging
 	// hardware). In any way, we release the metric unresolved to not block
 	// ordered operations for long time.
-	if _, found := entry.rows[index]; !found {
+	_, found := entry.rows[index]
+	if !found {
 		// Only update the agent if the user wants to
 		if s.minUpdateInterval > 0 {
 			if time.Since(entry.created) > s.minUpdateInterval {
 				// The minimum time between updates has passed so we are good to
 				// directly update the cache.
-				s.enqueue(agent)
-				return
+				s.refresh(agent)
+				return false
 			}
 			// The minimum time between updates has not yet passed so we
 			// need to defer the agent update to later.
@@ -110,6 +192,42 @@ func (s *store) lookup(agent string, index string) {
 	}
 
 	s.notify(agent, entry)
+
+	return found
+}
+
+// expired checks if the entry exceeded its TTL
+func (s *store) expired(entry *tagMap) bool {
+	ttl := s.ttl
+	if entry.ttl > 0 {
+		ttl = entry.ttl
+	}
+	return ttl > 0 && time.Since(entry.created) >= ttl
+}
+
+// jitteredTTL returns the TTL shifted by up to the configured jitter in both
+// directions. The offset is derived from the agent to be stable per entry.
+func (s *store) jitteredTTL(agent string) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(agent))
+	offset := time.Duration(h.Sum64()%uint64(2*s.ttlJitter+1)) - s.ttlJitter
+
+	return max(s.ttl+offset, time.Millisecond)
+}
+
+// recordRefresh remembers the time of the agent update and forgets about
+// agents not updated within the minimum refresh interval.
+func (s *store) recordRefresh(agent string) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	for k, t := range s.lastRefresh {
+		if now.Sub(t) >= s.minRefreshInterval {
+			delete(s.lastRefresh, k)
+		}
+	}
+	s.lastRefresh[agent] = now
 }
 
 func (s *store) destroy() {
@@ -119,5 +237,7 @@ func (s *store) destroy() {
 func (s *store) purge() {
 	s.Lock()
 	defer s.Unlock()
+	s.purging.Store(true)
+	defer s.purging.Store(false)
 	s.cache.Purge()
 }
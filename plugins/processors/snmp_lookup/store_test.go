@@ -1,6 +1,7 @@
 package snmp_lookup
 
 import (
+	"fmt"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -11,7 +12,7 @@ import (
 
 func TestAddBacklog(t *testing.T) {
 	var notifyCount atomic.Uint64
-	s := newStore(0, 0, 0, 0)
+	s := newStore(0, 0, 0, 0, 0, 0)
 	s.update = func(string) *tagMap { return nil }
 	s.notify = func(string, *tagMap) { notifyCount.Add(1) }
 	defer s.destroy()
@@ -41,7 +42,7 @@ func TestLookup(t *testing.T) {
 	minUpdateInterval := 50 * time.Millisecond
 	cacheTTL := config.Duration(2 * minUpdateInterval)
 	var notifyCount atomic.Uint64
-	s := newStore(defaultCacheSize, cacheTTL, defaultParallelLookups, config.Duration(minUpdateInterval))
+	s := newStore(defaultCacheSize, cacheTTL, 0, defaultParallelLookups, config.Duration(minUpdateInterval), 0)
 	s.update = func(string) *tagMap {
 		return &tagMap{
 			created: time.Now(),
@@ -96,3 +97,187 @@ func TestLookup(t *testing.T) {
 		return notifyCount.Load() == 4
 	}, time.Second, time.Millisecond)
 }
+
+func TestEvicted(t *testing.T) {
+	var evicted []string
+	s := newStore(1, defaultCacheTTL, 0, defaultParallelLookups, 0, 0)
+	s.evicted = func(agent string) { evicted = append(evicted, agent) }
+	defer s.destroy()
+
+	s.cache.Add("127.0.0.1", &tagMap{created: time.Now()})
+	require.Empty(t, evicted)
+
+	// Exceeding the cache size should evict the oldest agent
+	s.cache.Add("127.0.0.2", &tagMap{created: time.Now()})
+	require.Equal(t, []string{"127.0.0.1"}, evicted)
+
+	// Purging the cache should not count as eviction
+	s.purge()
+	require.Equal(t, []string{"127.0.0.1"}, evicted)
+	require.Zero(t, s.cache.Len())
+}
+
+func TestRefreshInterval(t *testing.T) {
+	tmr := tagMapRows{
+		"0": {"ifName": "eth0"},
+	}
+	cacheTTL := 20 * time.Millisecond
+	minRefreshInterval := 200 * time.Millisecond
+	var updateCount, notifyCount atomic.Uint64
+	s := newStore(defaultCacheSize, config.Duration(cacheTTL), 0, defaultParallelLookups, 0, config.Duration(minRefreshInterval))
+	s.update = func(string) *tagMap {
+		updateCount.Add(1)
+		return &tagMap{
+			created: time.Now(),
+			rows:    tmr,
+		}
+	}
+	s.notify = func(string, *tagMap) { notifyCount.Add(1) }
+	defer s.destroy()
+
+	// Initial lookup should cache entries
+	require.False(t, s.lookup("127.0.0.1", "0"))
+	require.Eventually(t, func() bool {
+		return notifyCount.Load() == 1
+	}, time.Second, time.Millisecond)
+	require.EqualValues(t, 1, updateCount.Load())
+
+	// Expired entries should still be served and the refresh be deferred
+	time.Sleep(2 * cacheTTL)
+	require.True(t, s.lookup("127.0.0.1", "0"))
+	require.EqualValues(t, 2, notifyCount.Load())
+	require.EqualValues(t, 1, updateCount.Load())
+	s.Lock()
+	require.Contains(t, s.deferredUpdates, "127.0.0.1")
+	s.Unlock()
+
+	// Wait for the deferred refresh
+	require.Eventually(t, func() bool {
+		return updateCount.Load() == 2 && notifyCount.Load() == 3
+	}, time.Second, time.Millisecond)
+}
+
+func TestRefreshIntervalMissingIndex(t *testing.T) {
+	tmr := tagMapRows{
+		"0": {"ifName": "eth0"},
+	}
+	minUpdateInterval := 20 * time.Millisecond
+	minRefreshInterval := 300 * time.Millisecond
+	var updateCount atomic.Uint64
+	s := newStore(defaultCacheSize, 0, 0, defaultParallelLookups, config.Duration(minUpdateInterval), config.Duration(minRefreshInterval))
+	s.update = func(string) *tagMap {
+		updateCount.Add(1)
+		return &tagMap{
+			created: time.Now(),
+			rows:    tmr,
+		}
+	}
+	s.notify = func(string, *tagMap) {}
+	defer s.destroy()
+
+	require.False(t, s.lookup("127.0.0.1", "0"))
+	require.Eventually(t, func() bool {
+		_, found := s.cache.Peek("127.0.0.1")
+		return found
+	}, time.Second, time.Millisecond)
+
+	// Missing indices must not trigger a walk within the refresh interval,
+	// neither deferred nor directly after the update interval passed.
+	require.False(t, s.lookup("127.0.0.1", "1"))
+	time.Sleep(2 * minUpdateInterval)
+	require.False(t, s.lookup("127.0.0.1", "1"))
+	time.Sleep(minRefreshInterval / 2)
+	require.EqualValues(t, 1, updateCount.Load())
+
+	// The deferred walk happens after the refresh interval
+	require.Eventually(t, func() bool {
+		return updateCount.Load() == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	cacheTTL := time.Hour
+	jitter := 10 * time.Minute
+	s := newStore(defaultCacheSize, config.Duration(cacheTTL), config.Duration(jitter), defaultParallelLookups, 0, 0)
+	defer s.destroy()
+
+	ttls := make(map[time.Duration]bool)
+	for i := range 10 {
+		agent := fmt.Sprintf("127.0.0.%d", i)
+		ttl := s.jitteredTTL(agent)
+		require.GreaterOrEqual(t, ttl, cacheTTL-jitter)
+		require.LessOrEqual(t, ttl, cacheTTL+jitter)
+		ttls[ttl] = true
+
+		// The TTL must be stable for the agent
+		require.Equal(t, ttl, s.jitteredTTL(agent))
+	}
+	require.Greater(t, len(ttls), 1)
+}
+
+func TestCacheTTLJitterExpiry(t *testing.T) {
+	tmr := tagMapRows{
+		"0": {"ifName": "eth0"},
+	}
+	cacheTTL := 50 * time.Millisecond
+	var updateCount, notifyCount atomic.Uint64
+	s := newStore(defaultCacheSize, config.Duration(cacheTTL), config.Duration(cacheTTL/2), defaultParallelLookups, 0, 0)
+	s.update = func(string) *tagMap {
+		updateCount.Add(1)
+		return &tagMap{
+			created: time.Now(),
+			rows:    tmr,
+		}
+	}
+	s.notify = func(string, *tagMap) { notifyCount.Add(1) }
+	defer s.destroy()
+
+	require.False(t, s.lookup("127.0.0.1", "0"))
+	require.Eventually(t, func() bool {
+		return notifyCount.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// The entry must expire after its jittered TTL and trigger a new walk
+	entry, ok := s.cache.Peek("127.0.0.1")
+	require.True(t, ok)
+	require.GreaterOrEqual(t, entry.ttl, cacheTTL/2)
+	require.LessOrEqual(t, entry.ttl, cacheTTL+cacheTTL/2)
+
+	time.Sleep(entry.ttl + 10*time.Millisecond)
+	require.False(t, s.lookup("127.0.0.1", "0"))
+	require.Eventually(t, func() bool {
+		return updateCount.Load() == 2 && notifyCount.Load() == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestCacheTTLJitterNoEviction(t *testing.T) {
+	tmr := tagMapRows{
+		"0": {"ifName": "eth0"},
+	}
+	cacheTTL := 50 * time.Millisecond
+	var updateCount, evictCount atomic.Uint64
+	s := newStore(defaultCacheSize, config.Duration(cacheTTL), config.Duration(cacheTTL/2), defaultParallelLookups, 0, 0)
+	s.update = func(string) *tagMap {
+		updateCount.Add(1)
+		return &tagMap{
+			created: time.Now(),
+			rows:    tmr,
+		}
+	}
+	s.notify = func(string, *tagMap) {}
+	s.evicted = func(string) { evictCount.Add(1) }
+	defer s.destroy()
+
+	require.False(t, s.lookup("127.0.0.1", "0"))
+	require.Eventually(t, func() bool {
+		return updateCount.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// Expiry of the jittered TTL must not count as eviction
+	time.Sleep(cacheTTL + cacheTTL/2 + 10*time.Millisecond)
+	require.False(t, s.lookup("127.0.0.1", "0"))
+	require.Eventually(t, func() bool {
+		return updateCount.Load() == 2
+	}, time.Second, time.Millisecond)
+	require.Zero(t, evictCount.Load())
+}
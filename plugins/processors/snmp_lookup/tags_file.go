@@ -0,0 +1,107 @@
+package snmp_lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal/snmp"
+)
+
+type tagFileEntry struct {
+	Name         string   `json:"name"`
+	Oid          string   `json:"oid"`
+	OidEnd       string   `json:"oid_end"`
+	Conversion   string   `json:"conversion"`
+	Destinations []string `json:"destinations"`
+}
+
+// loadTagsFile reads the tag definitions from the given file. The format is
+// determined by the file extension. JSON files must contain an array of
+// objects with 'name', 'oid' and optionally 'oid_end', 'conversion' and
+// 'destinations', all other files are treated as CSV with
+// 'name,oid[,conversion]' rows.
+func loadTagsFile(fn string) ([]tagDefinition, error) {
+	if strings.EqualFold(filepath.Ext(fn), ".json") {
+		return loadTagsJSON(fn)
+	}
+	return loadTagsCSV(fn)
+}
+
+func loadTagsJSON(fn string) ([]tagDefinition, error) {
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
+	}
+
+	var entries []tagFileEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %w", fn, err)
+	}
+
+	defs := make([]tagDefinition, 0, len(entries))
+	for i, e := range entries {
+		if e.Oid == "" {
+			return nil, fmt.Errorf("entry %d in %q is missing the OID", i, fn)
+		}
+		defs = append(defs, tagDefinition{
+			Field: snmp.Field{
+				Name:       e.Name,
+				Oid:        e.Oid,
+				OidEnd:     e.OidEnd,
+				Conversion: e.Conversion,
+			},
+			Destinations: e.Destinations,
+		})
+	}
+	return defs, nil
+}
+
+func loadTagsCSV(fn string) ([]tagDefinition, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q failed: %w", fn, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var defs []tagDefinition
+	line := 0
+	for {
+		line++
+		data, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("reading line %d in %q failed: %w", line, fn, err)
+		}
+		if len(data) < 2 || len(data) > 3 {
+			return nil, fmt.Errorf("line %d in %q has %d columns, requiring `name,oid[,conversion]`", line, fn, len(data))
+		}
+		if data[1] == "" {
+			return nil, fmt.Errorf("line %d in %q is missing the OID", line, fn)
+		}
+
+		def := tagDefinition{
+			Field: snmp.Field{
+				Name: data[0],
+				Oid:  data[1],
+			},
+		}
+		if len(data) > 2 {
+			def.Conversion = data[2]
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
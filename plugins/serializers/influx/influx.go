@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -51,6 +52,10 @@ type Serializer struct {
 	UintSupport   bool `toml:"influx_uint_support"`
 	OmitTimestamp bool `toml:"influx_omit_timestamp"`
 
+	// TimestampPrecision truncates the timestamps to the given unit, zero
+	// means nanoseconds.
+	TimestampPrecision time.Duration `toml:"-"`
+
 	bytesWritten int
 
 	buf    bytes.Buffer
@@ -156,7 +161,11 @@ func (s *Serializer) buildFooter(m telegraf.Metric) {
 	s.footer = s.footer[:0]
 	if !s.OmitTimestamp {
 		s.footer = append(s.footer, ' ')
-		s.footer = strconv.AppendInt(s.footer, m.Time().UnixNano(), 10)
+		ts := m.Time().UnixNano()
+		if s.TimestampPrecision > 0 {
+			ts /= int64(s.TimestampPrecision)
+		}
+		s.footer = strconv.AppendInt(s.footer, ts, 10)
 	}
 	s.footer = append(s.footer, '\n')
 }